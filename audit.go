@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// AmenityAuditRow holds the completeness of the platform attributes of one city
+type AmenityAuditRow struct {
+	City      string
+	Platforms int
+	// Complete is the percentage of platforms having all audited attributes
+	Complete string
+	// Tags holds the percentage of platforms having the attribute, in the order of AmenityAudit.Tags
+	Tags []string
+}
+
+// AmenityAudit is the result of the completeness audit of the platform attributes
+type AmenityAudit struct {
+	Tags []string
+	Rows []AmenityAuditRow
+}
+
+type amenityAuditCounter struct {
+	platforms int
+	complete  int
+	tagCounts map[string]int
+}
+
+// getMissingAmenityTags returns the audited attributes the element lacks
+func getMissingAmenityTags(e OsmElement) []string {
+	var missing []string
+	for _, tag := range config.AmenityAudit.Tags {
+		if e.Tags.Get(tag) == "" {
+			missing = append(missing, tag)
+		}
+	}
+	return missing
+}
+
+func formatPercentage(part, total int) string {
+	if total == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.0f %%", 100*float64(part)/float64(total))
+}
+
+// buildAmenityAudit counts the audited attributes of the platforms of the given stops per city
+func buildAmenityAudit(stops []MatchedBusStop) *AmenityAudit {
+	counters := make(map[string]*amenityAuditCounter)
+	total := &amenityAuditCounter{tagCounts: make(map[string]int)}
+	for _, stop := range stops {
+		city := stop.City
+		counter, exists := counters[city]
+		if !exists {
+			counter = &amenityAuditCounter{tagCounts: make(map[string]int)}
+			counters[city] = counter
+		}
+		for _, e := range stop.Elements {
+			if e.Tags.PublicTransport() != "platform" {
+				continue
+			}
+			missing := getMissingAmenityTags(e)
+			for _, c := range []*amenityAuditCounter{counter, total} {
+				c.platforms++
+				if len(missing) == 0 {
+					c.complete++
+				}
+				for _, tag := range config.AmenityAudit.Tags {
+					if e.Tags.Get(tag) != "" {
+						c.tagCounts[tag]++
+					}
+				}
+			}
+		}
+	}
+	var cities []string
+	for city := range counters {
+		if counters[city].platforms > 0 {
+			cities = append(cities, city)
+		}
+	}
+	sort.Strings(cities)
+
+	audit := AmenityAudit{Tags: config.AmenityAudit.Tags}
+	getRow := func(city string, c *amenityAuditCounter) AmenityAuditRow {
+		row := AmenityAuditRow{City: city, Platforms: c.platforms, Complete: formatPercentage(c.complete, c.platforms)}
+		for _, tag := range config.AmenityAudit.Tags {
+			row.Tags = append(row.Tags, formatPercentage(c.tagCounts[tag], c.platforms))
+		}
+		return row
+	}
+	for _, city := range cities {
+		audit.Rows = append(audit.Rows, getRow(city, counters[city]))
+	}
+	audit.Rows = append(audit.Rows, getRow("gesamt", total))
+	return &audit
+}
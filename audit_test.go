@@ -0,0 +1,21 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetMissingAmenityTags(t *testing.T) {
+	oldTags := config.AmenityAudit.Tags
+	defer func() {
+		config.AmenityAudit.Tags = oldTags
+	}()
+	config.AmenityAudit.Tags = []string{"bench", "bin", "shelter"}
+
+	// a tag without value is missing as well
+	e := OsmElement{Tags: OsmTags{"bench": "yes", "bin": "", "lit": "yes"}}
+	want := []string{"bin", "shelter"}
+	if missing := getMissingAmenityTags(e); !reflect.DeepEqual(missing, want) {
+		t.Errorf("getMissingAmenityTags() = %v, want %v", missing, want)
+	}
+}
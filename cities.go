@@ -0,0 +1,104 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// VvrStopName is a VVR stop name like "Sehlen (Rügen), Ort" split into its parts
+type VvrStopName struct {
+	// Municipality is the town or village, e.g. Sehlen
+	Municipality string
+	// Qualifier disambiguates municipalities with the same name, e.g. Rügen
+	Qualifier string
+	// Stop is the name of the stop within the municipality, e.g. Ort
+	Stop string
+}
+
+var municipalityQualifierRegex = regexp.MustCompile(`^(.*?)\s*\(([^)]*)\)$`)
+
+// parseVvrStopName splits a VVR stop name at the first comma into municipality
+// and stop. Names without comma only consist of the municipality.
+func parseVvrStopName(name string) VvrStopName {
+	var n VvrStopName
+	parts := strings.SplitN(name, ",", 2)
+	n.Municipality = strings.TrimSpace(parts[0])
+	if len(parts) == 2 {
+		n.Stop = strings.TrimSpace(parts[1])
+	}
+	if res := municipalityQualifierRegex.FindStringSubmatch(n.Municipality); res != nil {
+		n.Municipality = res[1]
+		n.Qualifier = res[2]
+	}
+	return n
+}
+
+// City returns the municipality including its qualifier as written by VVR
+func (n VvrStopName) City() string {
+	if n.Qualifier != "" {
+		return n.Municipality + " (" + n.Qualifier + ")"
+	}
+	return n.Municipality
+}
+
+// extractCities returns all cities used as prefix of VVR stop names
+func extractCities(vvr VvrData) []string {
+	var cities []string
+	known := make(map[string]bool)
+	for i := 0; i < len(vvr.CityResults); i++ {
+		for k := 0; k < len(vvr.CityResults[i].Result); k++ {
+			name := vvr.CityResults[i].Result[k].Value
+			if !strings.Contains(name, ",") {
+				continue
+			}
+			city := parseVvrStopName(name).City()
+			if !known[city] {
+				known[city] = true
+				cities = append(cities, city)
+			}
+		}
+	}
+	return cities
+}
+
+// getMunicipalityOfElement returns the municipality an OSM object is tagged
+// to be in via addr or is_in tags, or an empty string
+func getMunicipalityOfElement(e OsmElement) string {
+	for _, key := range []string{"addr:city", "is_in:city", "is_in:town", "is_in:village", "is_in:municipality"} {
+		if value := strings.TrimSpace(e.Tags.Get(key)); value != "" {
+			return value
+		}
+	}
+	// is_in lists the most specific place first, empty entries like in "," are skipped
+	for _, value := range strings.FieldsFunc(e.Tags.Get("is_in"), func(r rune) bool { return r == ',' || r == ';' }) {
+		if value = strings.TrimSpace(value); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// isSameMunicipality compares a municipality from OSM tags with the one of a VVR stop
+func isSameMunicipality(osmMunicipality string, vvrName VvrStopName) bool {
+	return strings.EqualFold(osmMunicipality, vvrName.Municipality) || strings.EqualFold(osmMunicipality, vvrName.City())
+}
+
+// getCityOfOsmRow determines the municipality of OSM objects which are not
+// matched to any VVR stop from their tags, a known city prefix in their name or
+// the nearest VVR stop
+func getCityOfOsmRow(stop MatchedBusStop, cities []string, vvrStops []MatchedBusStop, routeIndex RouteIndex) string {
+	for _, e := range stop.Elements {
+		if municipality := getMunicipalityOfElement(e); municipality != "" {
+			return municipality
+		}
+	}
+	if city, _ := splitCityPrefix(stop.Name, cities); city != "" {
+		return city
+	}
+	for _, e := range stop.Elements {
+		if suggestion := suggestNameForUnnamed(e, vvrStops, routeIndex); suggestion != nil {
+			return suggestion.Stop.City
+		}
+	}
+	return ""
+}
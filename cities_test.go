@@ -0,0 +1,24 @@
+package main
+
+import "testing"
+
+func TestGetMunicipalityOfElement(t *testing.T) {
+	tests := []struct {
+		tags         OsmTags
+		municipality string
+	}{
+		{OsmTags{}, ""},
+		{OsmTags{"addr:city": "Stralsund", "is_in": "Grimmen"}, "Stralsund"},
+		{OsmTags{"is_in:village": " Kloster "}, "Kloster"},
+		{OsmTags{"is_in": "Vitte, Insel Hiddensee"}, "Vitte"},
+		{OsmTags{"is_in": " ;Bergen auf Rügen;Vorpommern-Rügen"}, "Bergen auf Rügen"},
+		{OsmTags{"is_in": ","}, ""},
+		{OsmTags{"is_in": ";"}, ""},
+		{OsmTags{"is_in": " , ; "}, ""},
+	}
+	for _, test := range tests {
+		if municipality := getMunicipalityOfElement(OsmElement{Tags: test.tags}); municipality != test.municipality {
+			t.Errorf("getMunicipalityOfElement(%v) = %q, want %q", test.tags, municipality, test.municipality)
+		}
+	}
+}
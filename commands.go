@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+func printUsage() {
+	fmt.Fprintf(os.Stderr, `usage: %s [command] [flags]
+
+commands:
+  run       fetch, match and report in one go (default)
+  fetch     refresh the cached VVR and OSM data
+  match     match the cached data and save the result
+  report    render the HTML report from the saved result
+  explain   explain the matching of one VVR stop
+  daemon    refresh the data on its own schedule and update the report
+  serve     serve the report, map, exports and snapshots via HTTP
+
+use "%s <command> -h" for the flags of a command
+`, os.Args[0], os.Args[0])
+}
+
+// loadCachedData returns the cached VVR and OSM data without any request. It
+// fails if any of the data is missing instead of falling back to a request.
+func loadCachedData() (VvrData, OverpassData, error) {
+	dataDir := getDataDir()
+	for _, dataFile := range []string{vvrDataFile, overpassDataFile} {
+		dataFilePath := dataDir + string(os.PathSeparator) + dataFile
+		if _, err := os.Stat(dataFilePath); err != nil {
+			return VvrData{}, OverpassData{}, fmt.Errorf("no cached data %s, run fetch first: %v", dataFilePath, err)
+		}
+	}
+	var vvr VvrData
+	err := readCurrentJSON(&vvr)
+	if err != nil {
+		return VvrData{}, OverpassData{}, err
+	}
+	var overpass OverpassData
+	err = readCurrentJSON(&overpass)
+	if err != nil {
+		return VvrData{}, OverpassData{}, err
+	}
+	if len(vvr.CityResults) == 0 || len(overpass.Elements) == 0 {
+		return VvrData{}, OverpassData{}, errors.New("cached VVR or OSM data in " + dataDir + " is empty, run fetch first")
+	}
+	return vvr, overpass, nil
+}
+
+// printOverpassQuery prints the generated Overpass query
+func printOverpassQuery() error {
+	overpassQuery, err := buildOverpassQuery(config.OverpassQuery)
+	if err != nil {
+		return err
+	}
+	fmt.Println(overpassQuery)
+	return nil
+}
+
+// runCommand fetches the data, matches it and renders the report
+func runCommand(args []string) error {
+	fs := newFlagSet("run", "run [flags]")
+	addLockFileFlag(fs)
+	addTemplateDirFlag(fs)
+	addCacheDirFlag(fs)
+	addOutputDirFlag(fs)
+	addOfflineFlag(fs)
+	addSnapshotDirFlag(fs)
+	fs.BoolVar(printQuery, "print-query", false, "print the generated Overpass query and exit")
+	err := handleFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if *printQuery {
+		return printOverpassQuery()
+	}
+	err = createLockFile(lockFile)
+	if err != nil {
+		return err
+	}
+	defer removeLockFile(lockFile)
+	return updateReport()
+}
+
+// updateReport fetches the data unless in offline mode, matches it and writes
+// the match result, its snapshot, the GeoJSON export and the report. The caller
+// has to hold the lock file.
+func updateReport() error {
+	start := time.Now()
+	var vvr VvrData
+	var overpass OverpassData
+	var err error
+	if *offline {
+		vvr, overpass, err = loadCachedData()
+	} else {
+		vvr, overpass, err = fetchData(context.Background())
+	}
+	if err != nil {
+		return err
+	}
+	templateData := matchData(vvr, overpass)
+	templateData.RunDuration = time.Since(start).Round(time.Millisecond)
+	err = writeResult(templateData)
+	if err != nil {
+		return err
+	}
+	return writeTemplateToHTML(templateData)
+}
+
+// writeResult writes the match result, its snapshot and the GeoJSON export
+func writeResult(templateData TemplateData) error {
+	err := writeNewJSON(templateData)
+	if err != nil {
+		return err
+	}
+	err = writeResultSnapshot(templateData)
+	if err != nil {
+		log.Println("error writing snapshot of the match result:", err)
+	}
+	err = writeNewJSON(buildGeoJson(templateData))
+	if err != nil {
+		log.Println("error writing GeoJSON export:", err)
+	}
+	return nil
+}
+
+// fetchCommand only refreshes the cached data
+func fetchCommand(args []string) error {
+	fs := newFlagSet("fetch", "fetch [flags]")
+	addLockFileFlag(fs)
+	addCacheDirFlag(fs)
+	fs.BoolVar(printQuery, "print-query", false, "print the generated Overpass query and exit")
+	err := handleFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if *printQuery {
+		return printOverpassQuery()
+	}
+	err = createLockFile(lockFile)
+	if err != nil {
+		return err
+	}
+	defer removeLockFile(lockFile)
+	_, _, err = fetchData(context.Background())
+	return err
+}
+
+// matchCommand matches the cached data and saves the result for the report
+func matchCommand(args []string) error {
+	fs := newFlagSet("match", "match [flags]")
+	addLockFileFlag(fs)
+	addCacheDirFlag(fs)
+	addSnapshotDirFlag(fs)
+	addOutputDirFlag(fs)
+	err := handleFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	err = createLockFile(lockFile)
+	if err != nil {
+		return err
+	}
+	defer removeLockFile(lockFile)
+	start := time.Now()
+	vvr, overpass, err := loadCachedData()
+	if err != nil {
+		return err
+	}
+	templateData := matchData(vvr, overpass)
+	templateData.RunDuration = time.Since(start).Round(time.Millisecond)
+	err = writeResult(templateData)
+	if err != nil {
+		return err
+	}
+	stats := templateData.Stats
+	fmt.Printf("VVR stops: %d, without OSM object: %d\n", stats.VvrStops, stats.RemainingVvrStops)
+	fmt.Printf("OSM objects: %d, not matched: %d, without name: %d\n", stats.OsmStops, stats.RemainingOsmStops, stats.OsmStopsNoName)
+	fmt.Printf("warnings: %d\n", stats.WarningsSum)
+	return nil
+}
+
+// reportCommand renders the report from the saved match result
+func reportCommand(args []string) error {
+	fs := newFlagSet("report", "report [flags]")
+	addLockFileFlag(fs)
+	addTemplateDirFlag(fs)
+	addOutputDirFlag(fs)
+	err := handleFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	err = createLockFile(lockFile)
+	if err != nil {
+		return err
+	}
+	defer removeLockFile(lockFile)
+	var templateData TemplateData
+	err = readCurrentJSON(&templateData)
+	if err != nil {
+		return err
+	}
+	if templateData.GenDate.IsZero() {
+		return errors.New("no match result in " + outputDir + ", run match first")
+	}
+	return writeTemplateToHTML(templateData)
+}
+
+// explainCommand explains the matching of one VVR stop using the cached data
+func explainCommand(args []string) error {
+	fs := newFlagSet("explain", "explain [flags] <VVR stop name>")
+	addCacheDirFlag(fs)
+	addSnapshotDirFlag(fs)
+	err := handleFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("no VVR stop name given")
+	}
+	vvr, overpass, err := loadCachedData()
+	if err != nil {
+		return err
+	}
+	explainMatch(os.Stdout, strings.Join(fs.Args(), " "), vvr, overpass)
+	return nil
+}
+
+// serveCommand serves the generated files and allows to refresh them
+func serveCommand(args []string) error {
+	fs := newFlagSet("serve", "serve [flags]")
+	addLockFileFlag(fs)
+	addTemplateDirFlag(fs)
+	addCacheDirFlag(fs)
+	addOutputDirFlag(fs)
+	addOfflineFlag(fs)
+	addSnapshotDirFlag(fs)
+	addr := fs.String("addr", "localhost:8080", "address to listen on")
+	err := handleFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	return serve(*addr)
+}
+
+// daemonCommand keeps the report up to date until it is stopped
+func daemonCommand(args []string) error {
+	fs := newFlagSet("daemon", "daemon [flags]")
+	addLockFileFlag(fs)
+	addTemplateDirFlag(fs)
+	addCacheDirFlag(fs)
+	addOutputDirFlag(fs)
+	vvrInterval := fs.Duration("vvr-interval", cacheTimeVvrInHours*time.Hour, "interval to refresh the VVR data")
+	overpassInterval := fs.Duration("overpass-interval", cacheTimeOverpassInHours*time.Hour, "interval to refresh the OSM data")
+	err := handleFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if *vvrInterval <= 0 || *overpassInterval <= 0 {
+		return errors.New("intervals have to be positive")
+	}
+	return runDaemon(*vvrInterval, *overpassInterval)
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+)
+
+// Config holds the settings which can be changed via the config file
+type Config struct {
+	// OverpassEndpoints are the Overpass API interpreter URLs, tried in the given order
+	OverpassEndpoints []string `json:"overpass_endpoints"`
+	// OverpassQuery is used to generate the query for the OSM data
+	OverpassQuery OverpassQueryConfig `json:"overpass_query"`
+	// VvrDhids maps VVR IDs to the DHID (IFOPT) of the stop
+	VvrDhids map[string]string `json:"vvr_dhids"`
+	// MaxPlatformStopDistanceInMeters is the maximum plausible distance between
+	// a platform and its nearest stop position
+	MaxPlatformStopDistanceInMeters float64 `json:"max_platform_stop_distance"`
+	// AllowOtherLinesInRouteRef accepts lines in route_ref which are not served
+	// by VVR, e.g. lines of other operators at shared stops
+	AllowOtherLinesInRouteRef bool `json:"allow_other_lines_in_route_ref"`
+	// AmenityAudit configures the completeness audit of platform attributes
+	AmenityAudit AmenityAuditConfig `json:"amenity_audit"`
+	// Survey configures the list of stops to be verified on site
+	Survey SurveyConfig `json:"survey"`
+	// CanonicalNameForm is the name form proposed for the OSM objects of a stop,
+	// either "vvr" for the VVR spelling or "without_city" to omit the city prefix
+	CanonicalNameForm string `json:"canonical_name_form"`
+	// MaxUnnamedSuggestionDistanceInMeters is the maximum distance of a VVR stop
+	// to be suggested as name for an unnamed OSM object
+	MaxUnnamedSuggestionDistanceInMeters float64 `json:"max_unnamed_suggestion_distance"`
+	// NormalizationRules harmonize stop names before comparing them, they are applied in order
+	NormalizationRules []NormalizationRule `json:"normalization_rules"`
+	// NormalizationExamples of the config file are checked against the normalization rules when reading it
+	NormalizationExamples []NormalizationExample `json:"normalization_examples"`
+	// KeepSnapshots is the number of match results kept in the history, 0 keeps all
+	KeepSnapshots int `json:"keep_snapshots"`
+	// Paths overrides the default paths, relative ones are relative to the config file
+	Paths PathsConfig `json:"paths"`
+}
+
+// PathsConfig holds the paths the tool reads and writes, empty ones keep their default
+type PathsConfig struct {
+	CacheDir    string `json:"cache_dir"`
+	OutputDir   string `json:"output_dir"`
+	LockFile    string `json:"lock_file"`
+	TemplateDir string `json:"template_dir"`
+}
+
+// SurveyConfig configures the list of stops whose check_date is too old
+type SurveyConfig struct {
+	Enabled       bool `json:"enabled"`
+	MaxAgeInYears int  `json:"max_age_in_years"`
+}
+
+// AmenityAuditConfig configures the completeness audit of platform attributes
+type AmenityAuditConfig struct {
+	Enabled bool     `json:"enabled"`
+	Tags    []string `json:"tags"`
+}
+
+var config = newDefaultConfig()
+
+func newDefaultConfig() Config {
+	return Config{
+		OverpassEndpoints: []string{
+			"https://overpass-api.de/api/interpreter",
+			"https://overpass.kumi.systems/api/interpreter",
+		},
+		OverpassQuery: OverpassQueryConfig{
+			TimeoutInSeconds: 600,
+			Areas: []OverpassArea{
+				{Name: "Vorpommern-Rügen", Relation: 1739379},
+				{Name: "Graal-Müritz", Relation: 393349},
+				{Name: "Greifswald", Relation: 62363},
+				{Name: "Landhagen", Relation: 1432580},
+				{Name: "Sanitz", Relation: 393356},
+			},
+			Filters: []OverpassFilter{
+				{Types: "nw", Tags: []string{"public_transport=platform", "bus"}},
+				{Types: "node", Tags: []string{"public_transport=stop_position", "bus"}},
+				{Types: "node", Tags: []string{"highway=bus_stop"}},
+				{Types: "rel", Tags: []string{"type=public_transport"}},
+				{Types: "rel", Tags: []string{"type=route", "route=bus", "network=" + tag_network}},
+			},
+			Output: "center",
+		},
+		MaxPlatformStopDistanceInMeters: 50,
+		AmenityAudit: AmenityAuditConfig{
+			Tags: []string{"bench", "bin", "shelter", "lit", "tactile_paving", "wheelchair", "departures_board"},
+		},
+		Survey: SurveyConfig{
+			MaxAgeInYears: 3,
+		},
+		CanonicalNameForm:                    canonicalNameVvr,
+		MaxUnnamedSuggestionDistanceInMeters: 200,
+		KeepSnapshots:                        100,
+		// search and replace only in lower case
+		NormalizationRules: []NormalizationRule{
+			{Search: "(süderholz)", Replace: ""},
+			{Search: "focker", Replace: "fogger"},
+			{Search: "straße d. jugend", Replace: "straße der jugend"},
+			{Search: "elmemhorst", Replace: "elmenhorst"},
+			{Search: "gr.", Replace: "groß", Type: ruleTypeWord},
+			{Search: "lüdershg.", Replace: "lüdershagen"},
+			{Search: "bartelshg.ii", Replace: "bartelshagen ii"},
+			{Search: "c.-heydemann", Replace: "carl-heydemann"},
+			{Search: "h.-von-stephan", Replace: "heinrich-von-stephan"},
+			{Search: "e.-m.-arndt", Replace: "ernst-moritz-arndt"},
+			{Search: "h.-heine-ring", Replace: "heinrich-heine-ring"},
+			{Search: "deutsche rentenversicherung", Replace: "drv"},
+			{Search: "l.-feuchtwanger", Replace: "lion-feuchtwanger"},
+			{Search: "-", Replace: " "},
+			{Search: "/", Replace: " "},
+			{Search: ",", Replace: ""},
+			{Search: "ä", Replace: "ae"},
+			{Search: "ö", Replace: "oe"},
+			{Search: "ü", Replace: "ue"},
+			{Search: "ß", Replace: "ss"},
+			{Search: "(", Replace: ""},
+			{Search: ")", Replace: ""},
+			{Search: ".", Replace: ""},
+			{Search: "strasse", Replace: "str"},
+			{Search: "haupthst", Replace: "haupthaltestelle"},
+			{Search: "wpl", Replace: "wendeplatz"},
+			{Search: "krhs", Replace: "krankenhaus"},
+			{Search: `\s+`, Replace: " ", Type: ruleTypeRegex},
+		},
+	}
+}
+
+// readConfig reads the config file at path over the default config. A missing
+// config file is not an error, the defaults are used then.
+func readConfig(path string) error {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		if *verbose {
+			log.Printf("readConfig: config file %s does not exist, using defaults\n", path)
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	err = json.Unmarshal(b, &config)
+	if err != nil {
+		return err
+	}
+	if len(config.OverpassEndpoints) == 0 {
+		return errors.New("config: overpass_endpoints must not be empty")
+	}
+	if config.CanonicalNameForm != canonicalNameVvr && config.CanonicalNameForm != canonicalNameWithoutCity {
+		return fmt.Errorf("config: unknown canonical_name_form %s", config.CanonicalNameForm)
+	}
+	pipeline, err := compileNormalizationPipeline(config.NormalizationRules)
+	if err != nil {
+		return err
+	}
+	err = pipeline.checkExamples(config.NormalizationExamples)
+	if err != nil {
+		return err
+	}
+	normalizationPipeline = pipeline
+	_, err = buildOverpassQuery(config.OverpassQuery)
+	return err
+}
@@ -1,42 +1,27 @@
 package main
 
 import (
-	"flag"
 	"net/http"
+	"regexp"
 	"time"
 )
 
-const cacheDir = "cache"
+const backupFileEnding = ".bak"
 const cacheTimeOverpassInHours = 8
 const cacheTimeVvrInHours = 167
-const lockFile = ".lock"
-const outputDir = "output"
+const geoJsonFile = "haltestellenabgleich.geojson"
+const historyDir = "history"
+const httpCacheDir = "http"
+const mapFile = "map.html"
 const overpassDataFile = "overpass.json"
+const resultDataFile = "result.json"
+const statusTemplateName = "status"
 const templateFileEnding = ".go.tmpl"
 const templateName = "haltestellenabgleich"
 const tmplDirectory = "tmpl"
 const vvrDataFile = "vvr.json"
 const vvrSearchURL = "https://vvr.verbindungssuche.de/fpl/suhast.php?&query="
 
-// a bbox would be (53.948,12.2189,54.6937,13.7892)
-// normal Overpass-Turbo query:
-//
-//	[out:json][timeout:600];({{geocodeArea:"Vorpommern-Rügen"}};{{geocodeArea:"Graal-Müritz"}};{{geocodeArea:"Greifswald"}};)->.searchArea;(nw["public_transport"="platform"]["bus"](area.searchArea);node["public_transport"="stop_position"]["bus"](area.searchArea);node["highway"="bus_stop"](area.searchArea);rel["type"="public_transport"]["bus"](area.searchArea););out;
-//
-// needs to be converted to Overpass-API query (compact variant):
-//
-//	[out:json][timeout:600];(area(3601739379);area(3600393349);area(3600062363);)->.searchArea;(nwr["public_transport"="platform"]["bus"](area.searchArea);node["public_transport"="stop_position"]["bus"](area.searchArea);node["highway"="bus_stop"](area.searchArea);relation["type"="public_transport"]["bus"](area.searchArea););out;
-//
-// Vorpommern-Rügen = 3601739379
-// Graal-Müritz = 3600393349
-// Greifswald = 3600062363
-// Landhagen =  3601432580 // rund um Greifswald
-// Sanitz = 3600393356
-const overpassSearchArea = "area(3601739379);area(3600393349);area(3600062363);area(3601432580);area(3600393356);"
-const overpassURL = "http://overpass-api.de/api/interpreter?data="
-const overpassQueryPrefix = "[out:json][timeout:600];("
-const overpassQuerySuffix = ")->.searchArea;(nw[\"public_transport\"=\"platform\"][\"bus\"](area.searchArea);node[\"public_transport\"=\"stop_position\"][\"bus\"](area.searchArea);node[\"highway\"=\"bus_stop\"](area.searchArea);rel[\"type\"=\"public_transport\"](area.searchArea););out;"
-
 // tags
 const tag_network = "Verkehrsgesellschaft Vorpommern-Rügen"
 const tag_network_guid = "DE-MV-VVR"
@@ -46,6 +31,10 @@ const tag_operator = "Verkehrsgesellschaft Vorpommern-Rügen"
 //const uns_luett_bahn_tag_network = "Uns lütt Bahn"
 //const uns_luett_bahn_tag_operator = "Rügen-Bahnen"
 
+// name forms
+const canonicalNameVvr = "vvr"
+const canonicalNameWithoutCity = "without_city"
+
 // warnings
 const warning_network_tag_missing = "network tag is missing"
 const warning_network_guid_tag_missing = "network:guid tag is missing"
@@ -56,10 +45,42 @@ const warning_network_tag_not_correct = "network tag is not correct"
 const warning_network_guid_tag_not_correct = "network:guid tag is not correct"
 const warning_network_short_tag_not_correct = "network:short tag is not correct"
 const warning_operator_tag_not_correct = "operator is not correct"
+const warning_ifopt_tag_missing = "ref:IFOPT is missing"
+const warning_stop_area_missing = "no public_transport=stop_area relation contains the platforms and stop positions of this stop"
+const warning_stop_area_multiple = "platforms and stop positions are spread over several stop_area relations"
+const warning_stop_area_member_name = "name of stop_area member differs"
+const warning_stop_area_without_platform = "has no platform member"
+const warning_stop_area_without_stop_position = "has no stop_position member"
+const warning_stop_area_orphan = "is not member of any public_transport=stop_area relation"
+const warning_platform_without_stop_position = "platform has no stop_position"
+const warning_platform_stop_position_too_far = "platform is far away from its stop_position"
+const warning_bus_stop_not_platform = "highway=bus_stop without public_transport=platform"
+const warning_route_ref_lines_missing = "lacks lines served by VVR"
+const warning_route_ref_lines_extra = "contains lines not served by VVR"
+const warning_route_member_missing = "serves this stop according to VVR, but the stop is not member of its route relation"
+const warning_route_member_not_in_vvr = "contains this stop, but VVR does not list the line for it"
+const warning_names_spelled_differently = "name tags of the objects of this stop are spelled differently"
+const warning_names_city_prefix_differs = "name tags of the objects of this stop differ in the city prefix"
+const warning_municipality_differs = "object is tagged to be in a different municipality"
+const warning_ifopt_tag_not_correct = "ref:IFOPT does not belong to the DHID of the stop"
+
+// flags, registered for every command in newFlagSet
+var configFile = new(string)
+var debug = new(bool)
+var offline = new(bool)
+var printQuery = new(bool)
+var verbose = new(bool)
 
-// flags
-var debug = flag.Bool("d", false, "get debug output (implies verbose mode)")
-var verbose = flag.Bool("verbose", false, "verbose mode")
+// paths, can be changed via flags, environment variables or the config file
+var cacheDir = "cache"
+var lockFile = ".lock"
+var outputDir = "output"
+
+// templateDir may contain templates overriding the embedded ones
+var templateDir = ""
+
+// snapshotDir replaces cacheDir as data source in offline mode if set
+var snapshotDir = ""
 
 // non-const consts
 var alphabet = [30]string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k", "l", "m", "n", "o", "p", "q", "r", "s", "t", "u", "v", "w", "x", "y", "z", "ä", "ö", "ü", "ß"}
@@ -77,9 +98,6 @@ var ignoreBusStopsWithOperators = map[string]int{
 	"Anklamer Verkehrsgesellschaft mbH":          0,
 	"Verkehrsgesellschaft Vorpommern-Greifswald": 0,
 }
+var ifoptRegex = regexp.MustCompile(`^[a-zA-Z]{2}:[0-9]+:[0-9A-Za-z_]+(:[0-9A-Za-z_]+)*$`)
 var httpClient = &http.Client{Timeout: 1000 * time.Second}
 var ignoreVvrStops = []string{"Werkstatt, Stralsund (Workshop)", "Stralsund, Wagen defekt", "Stralsund, Sonderfahrt", "Stralsund, SEV", "Werkstatt, Ribnitz (Workshop)", "Stralsund, Probefahrt", "BH_G_S, (Workshop)", "BH_G_HG, (Workshop)", "Werkstatt, Bergen (Workshop)", "Stralsund, Am Hohen Graben", "Richtenberg, Mühlenbergstraße", "Kölzow", "Kloster, Kirchweg", "Neu Lüdershagen, II", "Vitte, Hafen", "Klevenow, Gemeinde", "Stralsund, Franzburg", "Schulenberg, Feuerwehr", "Papenhagen, Ersatzhaltestelle", "Hoikenhagen, Ersatzhaltestelle", "Stralsund, Bremer Str.", "Bergen, Industriestraße", "Barth, Vineta Sportarena", "Baabe, Haus des Gastes", "Baabe, Göhrener Chaussee", "Lassentin, Ausbau Ort", "Kölzow, Ausbau", "Richtenberg, Am Sportplatz", "Poggendorf, Alte Dorfstraße", "Stralsund, Altenpleen", "Stralsund, Betriebsfahrt", "Glowe, Wendeplatz", "Gager, Hafen", "Franzburg, Garthofstraße", "Dorow, Abzweig", "Damgarten, Bahnhof Ost", "Camper, Ortseingang", "Camitz, Försterei", "Balkenkoppel, Abzweig", "Groß Lehmhagen, Dorf", "Stralsund, Krönnevitz", "Stralsund, Kummerow", "Schulbus", "Stralsund, Velgast", "Stralsund, Tribseer Wiesen", "Stralsund, O.-Palme-Platz Wende", "Stralsund, Miltzow", "Stralsund, Klausdorf", "Stralsund, Jaromastraße", "Stralsund, Hexenplatz P+R", "Stralsund, Herzfeld"}
-
-// add searchStopName and replace elements only in lower case
-var searchStopName = []string{"(süderholz)", "focker", "straße d. jugend", "elmemhorst", "gr.", "lüdershg.", "bartelshg.ii", "c.-heydemann", "h.-von-stephan", "e.-m.-arndt", "h.-heine-ring", "deutsche rentenversicherung", "l.-feuchtwanger", "-", "/", ",", "ä", "ö", "ü", "ß", "(", ")", ".", "strasse", "haupthst", "wpl", "krhs", "   ", "  "}
-var replaceStopName = []string{"", "fogger", "straße der jugend", "elmenhorst", "groß", "lüdershagen", "bartelshagen ii", "carl-heydemann", "heinrich-von-stephan", "ernst-moritz-arndt", "heinrich-heine-ring", "drv", "lion-feuchtwanger", " ", " ", "", "ae", "oe", "ue", "ss", "", "", "", "str", "haupthaltestelle", "wendeplatz", "krankenhaus", " ", " "}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// daemonRetryInterval is the time to wait after a failed refresh, e.g. because
+// another run holds the lock file
+const daemonRetryInterval = time.Minute
+
+// Daemon keeps the data of the last refreshs to match it again when one of them changes
+type Daemon struct {
+	vvr       VvrData
+	overpass  OverpassData
+	isStarted bool
+}
+
+// refresh fetches the due data and updates the match result and the report if
+// anything changed. The first refresh uses the cache times, so a restart does
+// not fetch everything again, later ones always ask the servers.
+func (d *Daemon) refresh(ctx context.Context, isVvrDue bool, isOverpassDue bool) error {
+	err := createLockFile(lockFile)
+	if err != nil {
+		return err
+	}
+	defer removeLockFile(lockFile)
+	start := time.Now()
+
+	isChanged := !d.isStarted
+	if isVvrDue {
+		maxAge := time.Duration(0)
+		if !d.isStarted {
+			maxAge = cacheTimeVvrInHours * time.Hour
+		}
+		vvr, isVvrChanged, err := fetchVvrData(ctx, maxAge)
+		if err != nil {
+			return err
+		}
+		d.vvr = vvr
+		isChanged = isChanged || isVvrChanged
+	}
+	if isOverpassDue {
+		maxAge := time.Duration(0)
+		if !d.isStarted {
+			maxAge = cacheTimeOverpassInHours * time.Hour
+		}
+		overpass, isOverpassChanged, err := fetchOverpassData(ctx, maxAge)
+		if err != nil {
+			return err
+		}
+		d.overpass = overpass
+		isChanged = isChanged || isOverpassChanged
+	}
+	d.isStarted = true
+	if !isChanged {
+		if *verbose {
+			log.Println("daemon: data did not change, keeping the report")
+		}
+		return nil
+	}
+	if len(d.vvr.CityResults) == 0 || len(d.overpass.Elements) == 0 {
+		log.Println("daemon: VVR or OSM data is empty, not matching")
+		return nil
+	}
+	log.Println("daemon: data changed, matching again")
+	templateData := matchData(d.vvr, d.overpass)
+	templateData.RunDuration = time.Since(start).Round(time.Millisecond)
+	err = writeResult(templateData)
+	if err != nil {
+		return err
+	}
+	return writeTemplateToHTML(templateData)
+}
+
+// refreshUntilDone retries the refresh until it succeeds. It returns false if
+// ctx is done before.
+func (d *Daemon) refreshUntilDone(ctx context.Context, isVvrDue bool, isOverpassDue bool) bool {
+	for {
+		err := d.refresh(ctx, isVvrDue, isOverpassDue)
+		if ctx.Err() != nil {
+			return false
+		}
+		if err == nil {
+			return true
+		}
+		log.Printf("daemon: refresh failed, retrying in %s: %v\n", daemonRetryInterval, err)
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(daemonRetryInterval):
+		}
+	}
+}
+
+// runDaemon refreshes the VVR and OSM data on their own intervals until
+// SIGINT or SIGTERM is received
+func runDaemon(vvrInterval time.Duration, overpassInterval time.Duration) error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	log.Printf("daemon: refreshing VVR data every %s and OSM data every %s\n", vvrInterval, overpassInterval)
+
+	var d Daemon
+	if !d.refreshUntilDone(ctx, true, true) {
+		log.Println("daemon: shutting down")
+		return nil
+	}
+	vvrTimer := time.NewTimer(vvrInterval)
+	defer vvrTimer.Stop()
+	overpassTimer := time.NewTimer(overpassInterval)
+	defer overpassTimer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("daemon: shutting down")
+			return nil
+		case <-vvrTimer.C:
+			if !d.refreshUntilDone(ctx, true, false) {
+				log.Println("daemon: shutting down")
+				return nil
+			}
+			vvrTimer.Reset(vvrInterval)
+		case <-overpassTimer.C:
+			if !d.refreshUntilDone(ctx, false, true) {
+				log.Println("daemon: shutting down")
+				return nil
+			}
+			overpassTimer.Reset(overpassInterval)
+		}
+	}
+}
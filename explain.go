@@ -0,0 +1,258 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// maxExplainCandidates limits the number of OSM objects shown by explain
+const maxExplainCandidates = 20
+
+// minExplainSimilarity is the similarity from which an OSM object is shown as candidate
+const minExplainSimilarity = 0.6
+
+// getLevenshteinDistance returns the number of rune edits to turn a into b
+func getLevenshteinDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for k := range previous {
+		previous[k] = k
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for k := 1; k <= len(rb); k++ {
+			cost := 1
+			if ra[i-1] == rb[k-1] {
+				cost = 0
+			}
+			current[k] = minInt(minInt(previous[k]+1, current[k-1]+1), previous[k-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(rb)]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// getNameSimilarity returns a score between 0 and 1 for two normalized names
+func getNameSimilarity(a, b string) float64 {
+	maxLen := len([]rune(a))
+	if l := len([]rune(b)); l > maxLen {
+		maxLen = l
+	}
+	if maxLen == 0 {
+		return 0
+	}
+	return 1 - float64(getLevenshteinDistance(a, b))/float64(maxLen)
+}
+
+// getMatchScore compares an OSM name with the full VVR name and its stop part
+func getMatchScore(osmName string, vvrName string) float64 {
+	osmNormalized := normalizeStopName(osmName)
+	score := getNameSimilarity(osmNormalized, normalizeStopName(vvrName))
+	if stopPart := parseVvrStopName(vvrName).Stop; stopPart != "" {
+		if s := getNameSimilarity(osmNormalized, normalizeStopName(stopPart)); s > score {
+			score = s
+		}
+	}
+	return score
+}
+
+func printNormalizationSteps(w io.Writer, name string, indent string) {
+	for _, step := range normalizationPipeline.NormalizeWithSteps(name) {
+		rule := step.Rule.Type
+		if step.Rule.Search != "" {
+			if rule == "" {
+				rule = ruleTypeSubstring
+			}
+			rule += fmt.Sprintf(" %q -> %q", step.Rule.Search, step.Rule.Replace)
+		}
+		fmt.Fprintf(w, "%s%-40s %q\n", indent, rule+":", step.Result)
+	}
+}
+
+// findVvrStops returns all VVR stops with the given name, compared exactly or
+// after normalization if there is no exact match
+func findVvrStops(vvr VvrData, name string) []VvrBusStop {
+	var exact, normalized []VvrBusStop
+	for _, city := range vvr.CityResults {
+		for _, stop := range city.Result {
+			if stop.Value == name {
+				exact = append(exact, stop)
+			} else if normalizeStopName(stop.Value) == normalizeStopName(name) {
+				normalized = append(normalized, stop)
+			}
+		}
+	}
+	if len(exact) > 0 {
+		return exact
+	}
+	return normalized
+}
+
+// getSimilarVvrNames returns the n VVR stop names most similar to name
+func getSimilarVvrNames(vvr VvrData, name string, n int) []string {
+	scores := make(map[string]float64)
+	for _, city := range vvr.CityResults {
+		for _, stop := range city.Result {
+			scores[stop.Value] = getNameSimilarity(normalizeStopName(stop.Value), normalizeStopName(name))
+		}
+	}
+	var names []string
+	for vvrName := range scores {
+		names = append(names, vvrName)
+	}
+	sort.Slice(names, func(a, b int) bool {
+		if scores[names[a]] != scores[names[b]] {
+			return scores[names[a]] > scores[names[b]]
+		}
+		return names[a] < names[b]
+	})
+	if len(names) > n {
+		names = names[:n]
+	}
+	return names
+}
+
+// explainCandidate is an OSM object considered for the explained VVR stop
+type explainCandidate struct {
+	element OsmElement
+	score   float64
+	match   NameMatch
+	owner   *MatchedBusStop
+}
+
+// explainMatch writes how the VVR stop with the given name is matched to the
+// OSM objects: the normalization steps, the candidates with their scores, why
+// each was matched or rejected and which ignore list or override applied
+func explainMatch(w io.Writer, name string, vvr VvrData, overpass OverpassData) {
+	_, stopElements := takeMatchingElements(overpass.Elements, isBusRoute)
+	cities := extractCities(vvr)
+
+	vvrStops := findVvrStops(vvr, name)
+	if len(vvrStops) == 0 {
+		fmt.Fprintf(w, "VVR stop %q not found in VVR data, similar names are:\n", name)
+		for _, similar := range getSimilarVvrNames(vvr, name, 5) {
+			fmt.Fprintf(w, "  %s\n", similar)
+		}
+		return
+	}
+	vvrStop := vvrStops[0]
+	fmt.Fprintf(w, "VVR stop %q\n", vvrStop.Value)
+	fmt.Fprintf(w, "  VVR ID: %s\n", vvrStop.ID)
+	fmt.Fprintf(w, "  lines: %s\n", strings.Join(getLineNames(parseVvrLinien(vvrStop.Linien)), ", "))
+	vvrName := parseVvrStopName(vvrStop.Value)
+	fmt.Fprintf(w, "  municipality: %s, stop: %s\n", vvrName.City(), vvrName.Stop)
+	dhid := getDhidOfVvrStop(vvrStop)
+	if _, exists := config.VvrDhids[vvrStop.ID]; exists {
+		fmt.Fprintf(w, "  DHID: %s (override from vvr_dhids in config)\n", dhid)
+	} else if dhid != "" {
+		fmt.Fprintf(w, "  DHID: %s (VVR ID)\n", dhid)
+	} else {
+		fmt.Fprintln(w, "  DHID: unknown, only name matching is used")
+	}
+	for _, other := range vvrStops[1:] {
+		fmt.Fprintf(w, "  further VVR entry with the same name: VVR ID %s\n", other.ID)
+	}
+	for _, ignored := range ignoreVvrStops {
+		if ignored == vvrStop.Value {
+			fmt.Fprintln(w, "  ignored: the stop is listed in ignoreVvrStops and is not matched at all")
+			return
+		}
+	}
+
+	fmt.Fprintln(w, "normalization of the VVR name:")
+	printNormalizationSteps(w, vvrStop.Value, "  ")
+
+	mbs, _ := matchVvrWithOsm(vvr, stopElements, cities)
+	var target *MatchedBusStop
+	owners := make(map[string]*MatchedBusStop)
+	for i := range mbs {
+		if mbs[i].VvrID == vvrStop.ID {
+			target = &mbs[i]
+		}
+		for _, e := range mbs[i].Elements {
+			owners[getOsmElementKey(e.Type, e.ID)] = &mbs[i]
+		}
+	}
+	if target == nil {
+		fmt.Fprintln(w, "  not matched: the VVR stop was dropped before matching")
+		return
+	}
+	if target.Name != vvrStop.Value {
+		fmt.Fprintf(w, "duplicate: VVR ID %s is matched under the name %q, which came first in the VVR data\n", target.VvrID, target.Name)
+	}
+
+	var candidates []explainCandidate
+	for _, e := range stopElements {
+		c := explainCandidate{element: e, owner: owners[getOsmElementKey(e.Type, e.ID)]}
+		c.score = getMatchScore(e.Tags.Name(), target.Name)
+		c.match = matchOsmNameToVvrName(e, target.Name, cities)
+		if c.owner == target || c.match.IsMatching || c.score >= minExplainSimilarity || doesIfoptBelongToDhid(e.Tags.RefIFOPT(), target.DHID) {
+			candidates = append(candidates, c)
+		}
+	}
+	sort.SliceStable(candidates, func(a, b int) bool {
+		if (candidates[a].owner == target) != (candidates[b].owner == target) {
+			return candidates[a].owner == target
+		}
+		return candidates[a].score > candidates[b].score
+	})
+	fmt.Fprintf(w, "candidate OSM objects (%d, showing at most %d):\n", len(candidates), maxExplainCandidates)
+	if len(candidates) == 0 {
+		fmt.Fprintf(w, "  none, no OSM object has a similar name (similarity >= %.1f)\n", minExplainSimilarity)
+	}
+	for i, c := range candidates {
+		if i >= maxExplainCandidates {
+			break
+		}
+		e := c.element
+		fmt.Fprintf(w, "  %s/%d name=%q score=%.2f\n", e.Type, e.ID, e.Tags.Name(), c.score)
+		printNormalizationSteps(w, e.Tags.Name(), "    ")
+		for _, reason := range getExplainReasons(c, target) {
+			fmt.Fprintf(w, "    => %s\n", reason)
+		}
+	}
+}
+
+// getExplainReasons describes why a candidate was matched to the target stop or not
+func getExplainReasons(c explainCandidate, target *MatchedBusStop) []string {
+	var reasons []string
+	e := c.element
+	isIfoptMatch := c.owner != nil && doesIfoptBelongToDhid(e.Tags.RefIFOPT(), c.owner.DHID)
+	switch {
+	case c.owner == target && isIfoptMatch:
+		reasons = append(reasons, "matched via ref:IFOPT="+e.Tags.RefIFOPT()+" belonging to DHID "+target.DHID)
+	case c.owner == target && c.match.City != "":
+		reasons = append(reasons, "matched by name with city prefix "+c.match.City)
+	case c.owner == target:
+		reasons = append(reasons, "matched by name")
+	case c.owner != nil && isIfoptMatch:
+		reasons = append(reasons, "rejected: taken via ref:IFOPT="+e.Tags.RefIFOPT()+" by VVR stop "+c.owner.Name)
+	case c.owner != nil && c.match.IsMatching:
+		reasons = append(reasons, "rejected: already taken by name by VVR stop "+c.owner.Name+", which comes earlier in the VVR data")
+	case c.owner != nil:
+		reasons = append(reasons, "matched by name to VVR stop "+c.owner.Name)
+	}
+	if c.owner == nil {
+		reasons = append(reasons, "rejected: normalized names differ")
+	}
+	if municipality := getMunicipalityOfElement(e); c.owner == target && municipality != "" && !isSameMunicipality(municipality, parseVvrStopName(target.Name)) {
+		reasons = append(reasons, "object is tagged to be in "+municipality+", the report warns about it")
+	}
+	if target.DHID != "" && e.Tags.RefIFOPT() != "" && !doesIfoptBelongToDhid(e.Tags.RefIFOPT(), target.DHID) {
+		reasons = append(reasons, "ref:IFOPT="+e.Tags.RefIFOPT()+" does not belong to DHID "+target.DHID)
+	}
+	if _, exists := ignoreBusStopsWithOperators[e.Tags.Operator()]; exists {
+		reasons = append(reasons, "operator "+e.Tags.Operator()+" is listed in ignoreBusStopsWithOperators, the report row is ignored")
+	}
+	return reasons
+}
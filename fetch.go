@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/url"
+	"time"
+)
+
+// fetchVvrData updates the cached VVR data and returns it, responses younger
+// than maxAge are taken from the http cache. The returned bool reports whether
+// the data changed.
+func fetchVvrData(ctx context.Context, maxAge time.Duration) (VvrData, bool, error) {
+	if *verbose {
+		log.Println("reading data json file into memory")
+	}
+	var oldVvr VvrData
+	err := readCurrentJSON(&oldVvr)
+	if err != nil {
+		return VvrData{}, false, err
+	}
+	newVvr, isChanged := updateVvrData(ctx, oldVvr, maxAge)
+	if ctx.Err() != nil {
+		// do not replace the cache with partial data
+		return VvrData{}, false, ctx.Err()
+	}
+	if isChanged {
+		err = writeNewJSON(newVvr)
+		if err != nil {
+			log.Printf("error writing json with VVR data: %v\n", err)
+		}
+	}
+	return newVvr, isChanged, nil
+}
+
+// fetchOverpassData updates the cached OSM data and returns it, a response
+// younger than maxAge is taken from the http cache. The returned bool reports
+// whether the data changed.
+func fetchOverpassData(ctx context.Context, maxAge time.Duration) (OverpassData, bool, error) {
+	overpassQuery, err := buildOverpassQuery(config.OverpassQuery)
+	if err != nil {
+		return OverpassData{}, false, err
+	}
+	if *verbose {
+		log.Println("overpassQuery:", overpassQuery)
+	}
+	var oldOverpassData OverpassData
+	err = readCurrentJSON(&oldOverpassData)
+	if err != nil {
+		return OverpassData{}, false, err
+	}
+	newOverpassData, isWriteOverpassJson := updateOverpassData(ctx, overpassQuery, oldOverpassData, maxAge)
+	if ctx.Err() != nil {
+		return OverpassData{}, false, ctx.Err()
+	}
+	if isWriteOverpassJson {
+		err = writeNewJSON(newOverpassData)
+		if err != nil {
+			log.Printf("error writing json with overpass data: %v\n", err)
+		}
+	}
+	return newOverpassData, isWriteOverpassJson, nil
+}
+
+// updateVvrData queries the VVR search for every search word and returns the
+// results. Unchanged responses and failed requests reuse the data of oldVvr.
+// The returned bool reports whether any result changed.
+func updateVvrData(ctx context.Context, oldVvr VvrData, maxAge time.Duration) (VvrData, bool) {
+	lenSearchWords := len(alphabet) * len(alphabet)
+	searchWords := make([]string, lenSearchWords)
+	for i := 0; i < len(alphabet); i++ {
+		for k := 0; k < len(alphabet); k++ {
+			searchWords[i*len(alphabet)+k] = alphabet[i] + alphabet[k]
+		}
+	}
+	var newVvr VvrData
+	isAnyChanged := false
+	for i := 0; i < len(searchWords); i++ {
+		if ctx.Err() != nil {
+			log.Println("stopped querying VVR:", ctx.Err())
+			break
+		}
+		var newResult []VvrBusStop
+		oldVvrCity := getCityResultFromData(searchWords[i], oldVvr)
+		getURL := vvrSearchURL + url.QueryEscape(searchWords[i])
+		isChanged, err := getJson(ctx, getURL, maxAge, &newResult)
+		if err != nil {
+			log.Println("error getting http json for", getURL)
+			log.Println("error is", err)
+			if oldVvrCity != nil {
+				log.Printf("reusing old cache data for %s due to the GET error\n", searchWords[i])
+				newVvr.CityResults = append(newVvr.CityResults, *oldVvrCity)
+			}
+			continue
+		}
+		if !isChanged && oldVvrCity != nil {
+			if *debug {
+				log.Printf("response for %s did not change since %s, reusing old result\n", oldVvrCity.SearchWord, oldVvrCity.ResultTimeStamp)
+			}
+			newVvr.CityResults = append(newVvr.CityResults, *oldVvrCity)
+			continue
+		}
+		if !isChanged {
+			err = decodeCachedJson(getURL, nil, &newResult)
+			if err != nil {
+				log.Println("error decoding cached response for", getURL, err)
+				continue
+			}
+		}
+		var newVvrCity VvrCity
+		newVvrCity.SearchWord = searchWords[i]
+		newVvrCity.ResultTimeStamp = time.Now()
+		newVvrCity.Result = newResult
+		newVvr.CityResults = append(newVvr.CityResults, newVvrCity)
+		isAnyChanged = true
+	}
+	if len(newVvr.CityResults) != len(oldVvr.CityResults) {
+		isAnyChanged = true
+	}
+	return newVvr, isAnyChanged
+}
+
+// updateOverpassData sends the Overpass query to the configured endpoints in
+// order until one of them answers and returns its result. An unchanged response
+// or failing endpoints reuse oldOverpassData. The returned bool reports whether
+// the data changed and needs to be written to the cache.
+func updateOverpassData(ctx context.Context, overpassQuery string, oldOverpassData OverpassData, maxAge time.Duration) (OverpassData, bool) {
+	form := url.Values{"data": {overpassQuery}}
+	for i := 0; i < len(config.OverpassEndpoints); i++ {
+		endpoint := config.OverpassEndpoints[i]
+		if *verbose {
+			log.Println("querying overpass endpoint", endpoint)
+		}
+		var newOverpassData OverpassData
+		isChanged, err := postJson(ctx, endpoint, form, maxAge, &newOverpassData)
+		if err != nil {
+			log.Println("error getting http json from", endpoint)
+			log.Println("error is", err)
+			continue
+		}
+		if !isChanged && len(oldOverpassData.Elements) > 0 && oldOverpassData.Endpoint == endpoint {
+			if *verbose {
+				log.Println("reusing old overpass data from cache, response did not change")
+			}
+			return oldOverpassData, false
+		}
+		if !isChanged {
+			err = decodeCachedJson(endpoint, form, &newOverpassData)
+			if err != nil {
+				log.Println("error decoding cached overpass response", err)
+				continue
+			}
+		}
+		newOverpassData.Endpoint = endpoint
+		return newOverpassData, true
+	}
+	log.Println("reusing old overpass cache data, because no endpoint answered")
+	return oldOverpassData, false
+}
@@ -0,0 +1,52 @@
+package main
+
+import "math"
+
+// Coordinates returns a representative point of the element: the position of
+// a node, otherwise the center, the middle of the bounds or the mean of the
+// geometry of a way or relation. The bool is false if the element has no
+// coordinates, e.g. because the data was queried without center output.
+func (e OsmElement) Coordinates() (float64, float64, bool) {
+	if e.Type == "node" {
+		return e.Lat, e.Lon, e.Lat != 0 || e.Lon != 0
+	}
+	if e.Center != nil {
+		return e.Center.Lat, e.Center.Lon, true
+	}
+	if e.Bounds != nil {
+		return (e.Bounds.MinLat + e.Bounds.MaxLat) / 2, (e.Bounds.MinLon + e.Bounds.MaxLon) / 2, true
+	}
+	if len(e.Geometry) > 0 {
+		var lat, lon float64
+		for _, c := range e.Geometry {
+			lat += c.Lat
+			lon += c.Lon
+		}
+		return lat / float64(len(e.Geometry)), lon / float64(len(e.Geometry)), true
+	}
+	return 0, 0, false
+}
+
+// earthRadiusInMeters is the mean earth radius
+const earthRadiusInMeters = 6371000
+
+// getDistanceInMeters returns the great-circle distance between two points
+func getDistanceInMeters(lat1, lon1, lat2, lon2 float64) float64 {
+	phi1 := lat1 * math.Pi / 180
+	phi2 := lat2 * math.Pi / 180
+	deltaPhi := (lat2 - lat1) * math.Pi / 180
+	deltaLambda := (lon2 - lon1) * math.Pi / 180
+	a := math.Sin(deltaPhi/2)*math.Sin(deltaPhi/2) + math.Cos(phi1)*math.Cos(phi2)*math.Sin(deltaLambda/2)*math.Sin(deltaLambda/2)
+	return 2 * earthRadiusInMeters * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
+
+// getDistanceBetweenElements returns the distance between two OSM elements, the
+// bool is false if one of them has no coordinates
+func getDistanceBetweenElements(a, b OsmElement) (float64, bool) {
+	latA, lonA, okA := a.Coordinates()
+	latB, lonB, okB := b.Coordinates()
+	if !okA || !okB {
+		return 0, false
+	}
+	return getDistanceInMeters(latA, lonA, latB, lonB), true
+}
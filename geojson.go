@@ -0,0 +1,56 @@
+package main
+
+// GeoJsonGeometry is a GeoJSON point
+type GeoJsonGeometry struct {
+	Type string `json:"type"`
+	// Coordinates are longitude and latitude
+	Coordinates [2]float64 `json:"coordinates"`
+}
+
+// GeoJsonFeature is one row of the match result as GeoJSON feature
+type GeoJsonFeature struct {
+	Type       string                 `json:"type"`
+	Geometry   GeoJsonGeometry        `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// GeoJsonFeatureCollection holds the rows of the match result which have an OSM object
+type GeoJsonFeatureCollection struct {
+	Type     string           `json:"type"`
+	Features []GeoJsonFeature `json:"features"`
+}
+
+// buildGeoJson returns the rows of the match result with OSM objects as
+// points. VVR stops without OSM object have no position and are left out.
+func buildGeoJson(templateData TemplateData) GeoJsonFeatureCollection {
+	collection := GeoJsonFeatureCollection{Type: "FeatureCollection", Features: []GeoJsonFeature{}}
+	for _, row := range templateData.Rows {
+		if row.Lat == 0 && row.Lon == 0 {
+			continue
+		}
+		feature := GeoJsonFeature{
+			Type: "Feature",
+			Geometry: GeoJsonGeometry{
+				Type:        "Point",
+				Coordinates: [2]float64{row.Lon, row.Lat},
+			},
+			Properties: map[string]interface{}{
+				"id":                row.ID,
+				"vvr_id":            row.VvrID,
+				"dhid":              row.DHID,
+				"name":              row.Name,
+				"city":              row.City,
+				"lines":             row.Lines,
+				"is_ignored":        row.IsIgnored,
+				"is_in_vvr":         row.IsInVVR,
+				"is_in_osm":         row.IsInOSM,
+				"nr_bus_stops":      row.NrBusStops,
+				"nr_platforms":      row.NrPlatforms,
+				"nr_stop_positions": row.NrStopPositions,
+				"warnings":          row.Warnings,
+			},
+		}
+		collection.Features = append(collection.Features, feature)
+	}
+	return collection
+}
@@ -1,3 +1,3 @@
 module github.com/Strubbl/vvr-haltestellenabgleich
 
-go 1.17
+go 1.19
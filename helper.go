@@ -2,21 +2,41 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"log"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 )
 
-func removeLockFile(lf string) {
-	if *verbose {
-		log.Printf("removeLockFile: trying to delete %s\n", lf)
+// writeFileAtomically writes b to a temporary file next to path and renames it
+// to path afterwards, so readers never see a partially written file
+func writeFileAtomically(path string, b []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	tmpPath := f.Name()
+	_, err = f.Write(b)
+	if err == nil {
+		err = f.Sync()
+	}
+	closeErr := f.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmpPath, 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, path)
 	}
-	err := os.Remove(lf)
 	if err != nil {
-		log.Printf("removeLockFile: error while removing lock file %s\n", lf)
-		log.Panic(err)
+		os.Remove(tmpPath)
+		return err
 	}
+	return nil
 }
 
 func printElapsedTime(start time.Time) {
@@ -25,11 +45,38 @@ func printElapsedTime(start time.Time) {
 	}
 }
 
-func handleFlags() {
+// newFlagSet returns the flag set of a command with the flags all commands share
+func newFlagSet(command string, usage string) *flag.FlagSet {
+	fs := flag.NewFlagSet(command, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s %s\n", os.Args[0], usage)
+		fs.PrintDefaults()
+	}
+	fs.BoolVar(debug, "d", false, "get debug output (implies verbose mode)")
+	fs.BoolVar(verbose, "verbose", false, "verbose mode")
+	fs.StringVar(configFile, "config", "config.json", "path to the config file (env VVR_CONFIG)")
+	return fs
+}
+
+// addOfflineFlag registers the flag to use cached data only, for commands which fetch data
+func addOfflineFlag(fs *flag.FlagSet) {
+	fs.BoolVar(offline, "offline", false, "use the cached data only, never send any request")
+}
+
+// addSnapshotDirFlag registers the flag to read the data from a snapshot directory
+func addSnapshotDirFlag(fs *flag.FlagSet) {
+	fs.StringVar(&snapshotDir, "snapshot-dir", "", "read the VVR and OSM data from this directory instead of the cache and never send any request")
+}
+
+// handleFlags parses the flags of a command and reads the config
+func handleFlags(fs *flag.FlagSet, args []string) error {
 	// Flag handling
-	flag.Parse()
-	if *debug && len(flag.Args()) > 0 {
-		log.Printf("non-flag args=%v\n", strings.Join(flag.Args(), " "))
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if *debug && len(fs.Args()) > 0 {
+		log.Printf("non-flag args=%v\n", strings.Join(fs.Args(), " "))
 	}
 
 	if *verbose && !*debug {
@@ -40,5 +87,24 @@ func handleFlags() {
 		// debug implies verbose
 		*verbose = true
 	}
-
+	if snapshotDir != "" {
+		// a snapshot is never updated
+		*offline = true
+	}
+	isConfigFlagSet := false
+	fs.Visit(func(f *flag.Flag) {
+		isConfigFlagSet = isConfigFlagSet || f.Name == "config"
+	})
+	if value := os.Getenv("VVR_CONFIG"); value != "" && !isConfigFlagSet {
+		*configFile = value
+	}
+	err = readConfig(*configFile)
+	if err != nil {
+		return fmt.Errorf("error reading config file %s: %v", *configFile, err)
+	}
+	applyPaths(fs)
+	if *debug {
+		log.Printf("paths: cache %s, output %s, lock file %s, templates %q\n", cacheDir, outputDir, lockFile, templateDir)
+	}
+	return nil
 }
@@ -1,32 +1,74 @@
 package main
 
 import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
 	"html/template"
+	"io"
 	"log"
 	"os"
+	"path/filepath"
 )
 
-func writeTemplateToHTML(templateData TemplateData) {
-	if _, err := os.Stat(outputDir); os.IsNotExist(err) {
-		os.Mkdir(outputDir, os.ModePerm)
+// embeddedTemplates are the default templates, so the binary is self-contained
+//
+//go:embed tmpl
+var embeddedTemplates embed.FS
+
+// readTemplateFile returns the template file from the template directory if it
+// is there, otherwise the embedded one
+func readTemplateFile(fileName string) ([]byte, error) {
+	if templateDir != "" {
+		b, err := os.ReadFile(filepath.Join(templateDir, fileName))
+		if err == nil {
+			return b, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		if *verbose {
+			log.Printf("readTemplateFile: %s not in %s, using the embedded one\n", fileName, templateDir)
+		}
 	}
-	f, err := os.Create(outputDir + string(os.PathSeparator) + templateName + ".html")
+	return embeddedTemplates.ReadFile(tmplDirectory + "/" + fileName)
+}
+
+// parseTemplate parses the template with the given name
+func parseTemplate(name string) (*template.Template, error) {
+	b, err := readTemplateFile(name + templateFileEnding)
 	if err != nil {
-		log.Println("writeTemplateToHTML", err)
+		return nil, err
 	}
-	defer func() {
-		if err := f.Close(); err != nil {
-			log.Println("writeTemplateToHTML", err)
-		}
-	}()
-
-	htmlSource, err := template.New(templateName + templateFileEnding).Funcs(template.FuncMap{
+	return template.New(name + templateFileEnding).Funcs(template.FuncMap{
 		"unescapeHTML": func(input string) template.HTML {
 			return template.HTML(input)
 		},
-	}).ParseFiles(tmplDirectory + "/" + templateName + templateFileEnding)
+	}).Parse(string(b))
+}
+
+// executeReport renders the report of templateData to w
+func executeReport(w io.Writer, templateData TemplateData) error {
+	htmlSource, err := parseTemplate(templateName)
+	if err != nil {
+		return err
+	}
+	return htmlSource.Execute(w, templateData)
+}
+
+// writeTemplateToHTML renders the report into the output directory. The last
+// report is kept if rendering fails.
+func writeTemplateToHTML(templateData TemplateData) error {
+	err := os.MkdirAll(outputDir, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	// render into memory first, so a failing template never replaces the last report
+	var buf bytes.Buffer
+	err = executeReport(&buf, templateData)
 	if err != nil {
-		log.Println("writeTemplateToHTML", err)
+		return fmt.Errorf("rendering the report failed: %v", err)
 	}
-	htmlSource.Execute(f, templateData)
+	return writeFileAtomically(outputDir+string(os.PathSeparator)+templateName+".html", buf.Bytes())
 }
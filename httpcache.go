@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// CachedResponse holds the meta data of a raw HTTP response stored in the http cache
+type CachedResponse struct {
+	URL          string    `json:"url"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	FetchedAt    time.Time `json:"fetched_at"`
+	CheckedAt    time.Time `json:"checked_at"`
+}
+
+func getHttpCacheKey(requestURL string, form url.Values) string {
+	key := requestURL
+	if form != nil {
+		key += "\n" + form.Encode()
+	}
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func getHttpCachePaths(key string) (string, string) {
+	dir := cacheDir + string(os.PathSeparator) + httpCacheDir + string(os.PathSeparator)
+	return dir + key + ".json", dir + key + ".body"
+}
+
+// readCachedResponse returns the cached meta data and body for the given key,
+// both are nil if there is nothing in the cache yet
+func readCachedResponse(key string) (*CachedResponse, []byte, error) {
+	metaPath, bodyPath := getHttpCachePaths(key)
+	b, err := os.ReadFile(metaPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	var meta CachedResponse
+	err = json.Unmarshal(b, &meta)
+	if err != nil {
+		return nil, nil, err
+	}
+	body, err := os.ReadFile(bodyPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &meta, body, nil
+}
+
+func writeCachedResponse(key string, meta CachedResponse, body []byte) error {
+	metaPath, bodyPath := getHttpCachePaths(key)
+	err := os.MkdirAll(cacheDir+string(os.PathSeparator)+httpCacheDir, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		err = writeFileAtomically(bodyPath, body)
+		if err != nil {
+			return err
+		}
+	}
+	return writeFileAtomically(metaPath, b)
+}
+
+// fetchCached returns the body of url. If form is not nil, it is sent as POST
+// body instead of doing a GET request. Within maxAge since the last check the
+// cached body is returned without any request. Otherwise a conditional request
+// using ETag and Last-Modified of the cached response is sent. The returned bool
+// reports whether the body differs from the one in the cache.
+func fetchCached(ctx context.Context, requestURL string, form url.Values, maxAge time.Duration) ([]byte, bool, error) {
+	if *offline {
+		return nil, false, errors.New("offline mode, refusing to request " + requestURL)
+	}
+	key := getHttpCacheKey(requestURL, form)
+	meta, body, err := readCachedResponse(key)
+	if err != nil {
+		log.Println("fetchCached: ignoring unreadable cache entry for", requestURL, err)
+		meta = nil
+		body = nil
+	}
+	if meta != nil && time.Since(meta.CheckedAt) < maxAge {
+		if *debug {
+			log.Printf("fetchCached: using cached response for %s, last checked at %s\n", requestURL, meta.CheckedAt)
+		}
+		return body, false, nil
+	}
+
+	var req *http.Request
+	if form != nil {
+		req, err = http.NewRequestWithContext(ctx, http.MethodPost, requestURL, strings.NewReader(form.Encode()))
+		if err == nil {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+	} else {
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if meta != nil {
+		if meta.ETag != "" {
+			req.Header.Set("If-None-Match", meta.ETag)
+		}
+		if meta.LastModified != "" {
+			req.Header.Set("If-Modified-Since", meta.LastModified)
+		}
+	}
+	r, err := httpClient.Do(req)
+	if err != nil {
+		return nil, false, err
+	}
+	defer r.Body.Close()
+
+	now := time.Now()
+	if r.StatusCode == http.StatusNotModified && meta != nil {
+		if *debug {
+			log.Println("fetchCached: not modified", requestURL)
+		}
+		meta.CheckedAt = now
+		err = writeCachedResponse(key, *meta, nil)
+		if err != nil {
+			log.Println("fetchCached: error while updating cache entry", err)
+		}
+		return body, false, nil
+	}
+	if r.StatusCode != http.StatusOK {
+		return nil, false, fmt.Errorf("unexpected HTTP status %s for %s", r.Status, requestURL)
+	}
+	newBody, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, false, err
+	}
+	isChanged := meta == nil || !bytes.Equal(body, newBody)
+	newMeta := CachedResponse{
+		URL:          requestURL,
+		ETag:         r.Header.Get("ETag"),
+		LastModified: r.Header.Get("Last-Modified"),
+		FetchedAt:    now,
+		CheckedAt:    now,
+	}
+	if !isChanged {
+		newMeta.FetchedAt = meta.FetchedAt
+	}
+	err = writeCachedResponse(key, newMeta, newBody)
+	if err != nil {
+		log.Println("fetchCached: error while writing cache entry", err)
+	}
+	return newBody, isChanged, nil
+}
+
+// decodeCachedJson decodes the cached body of url and form into target without any request
+func decodeCachedJson(requestURL string, form url.Values, target interface{}) error {
+	_, body, err := readCachedResponse(getHttpCacheKey(requestURL, form))
+	if err != nil {
+		return err
+	}
+	if body == nil {
+		return fmt.Errorf("no cached response for %s", requestURL)
+	}
+	return json.Unmarshal(body, target)
+}
@@ -1,18 +1,32 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
+	"net/url"
 	"os"
+	"time"
 )
 
+// getDataDir returns the directory to read the VVR and OSM data from
+func getDataDir() string {
+	if snapshotDir != "" {
+		return snapshotDir
+	}
+	return cacheDir
+}
+
+// readCurrentJSON reads the cached data or the match result into i, it is left
+// empty if there is no file yet. A corrupt cache file is replaced by its backup.
 func readCurrentJSON(i interface{}) error {
 	if *debug {
 		log.Println("readCurrentJSON")
 	}
 	var jsonFilePath string
+	isCache := false
 	if *debug {
 		log.Println("readCurrentJSON: given type:")
 		log.Printf("%T\n", i)
@@ -22,16 +36,21 @@ func readCurrentJSON(i interface{}) error {
 		if *debug {
 			log.Println("readCurrentJSON: found *VvrData type")
 		}
-		jsonFilePath = cacheDir + string(os.PathSeparator) + vvrDataFile
+		jsonFilePath = getDataDir() + string(os.PathSeparator) + vvrDataFile
+		isCache = true
 	case *OverpassData:
 		if *debug {
 			log.Println("readCurrentJSON: found *OverpassData type")
 		}
-		jsonFilePath = cacheDir + string(os.PathSeparator) + overpassDataFile
-
+		jsonFilePath = getDataDir() + string(os.PathSeparator) + overpassDataFile
+		isCache = true
+	case *TemplateData:
+		if *debug {
+			log.Println("readCurrentJSON: found *TemplateData type")
+		}
+		jsonFilePath = outputDir + string(os.PathSeparator) + resultDataFile
 	default:
-		log.Fatalln("readCurrentJSON: unkown type for reading json")
-		return nil
+		return errors.New("readCurrentJSON: unkown type for reading json")
 	}
 
 	if *debug {
@@ -57,37 +76,101 @@ func readCurrentJSON(i interface{}) error {
 		if *debug {
 			log.Println("readCurrentJSON: error while json.Unmarshal", err)
 		}
+		if isCache {
+			return readBackupJSON(jsonFilePath, i, err)
+		}
 		return err
 	}
 	return nil
 }
 
+// resetData empties the data i points to after a failed json.Unmarshal
+func resetData(i interface{}) {
+	switch v := i.(type) {
+	case *VvrData:
+		*v = VvrData{}
+	case *OverpassData:
+		*v = OverpassData{}
+	}
+}
+
+// readBackupJSON reads the backup of a corrupt cache file into i. If the backup
+// is unusable as well, i stays empty, so the data is fetched again.
+func readBackupJSON(jsonFilePath string, i interface{}, corruptErr error) error {
+	log.Printf("readBackupJSON: cache file %s is corrupt, using its backup: %v\n", jsonFilePath, corruptErr)
+	resetData(i)
+	b, err := os.ReadFile(jsonFilePath + backupFileEnding)
+	if err == nil {
+		err = json.Unmarshal(b, i)
+	}
+	if err != nil {
+		log.Printf("readBackupJSON: backup of %s is unusable, starting with empty data: %v\n", jsonFilePath, err)
+		resetData(i)
+	}
+	return nil
+}
+
+// backupJSONFile keeps the current content of path as backup before it is
+// replaced. A corrupt file never replaces the last good backup.
+func backupJSONFile(path string) error {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !json.Valid(b) {
+		log.Printf("backupJSONFile: not keeping corrupt %s as backup\n", path)
+		return nil
+	}
+	return writeFileAtomically(path+backupFileEnding, b)
+}
+
 func writeNewJSON(i interface{}) error {
 	if *debug {
 		log.Println("writeNewJSON: given type:")
 		log.Printf("%T\n", i)
 	}
-	var jsonFilePath string
+	var dir, jsonFilePath string
+	isCache := false
 	switch i.(type) {
 	case VvrData:
 		if *debug {
 			log.Println("found VvrData type")
 		}
-		if _, err := os.Stat(cacheDir); os.IsNotExist(err) {
-			os.Mkdir(cacheDir, os.ModePerm)
-		}
+		dir = cacheDir
 		jsonFilePath = cacheDir + string(os.PathSeparator) + vvrDataFile
+		isCache = true
 	case OverpassData:
 		if *debug {
 			log.Println("found OverpassData type")
 		}
-		if _, err := os.Stat(cacheDir); os.IsNotExist(err) {
-			os.Mkdir(cacheDir, os.ModePerm)
-		}
+		dir = cacheDir
 		jsonFilePath = cacheDir + string(os.PathSeparator) + overpassDataFile
+		isCache = true
+	case TemplateData:
+		if *debug {
+			log.Println("found TemplateData type")
+		}
+		dir = outputDir
+		jsonFilePath = outputDir + string(os.PathSeparator) + resultDataFile
+	case GeoJsonFeatureCollection:
+		if *debug {
+			log.Println("found GeoJsonFeatureCollection type")
+		}
+		dir = outputDir
+		jsonFilePath = outputDir + string(os.PathSeparator) + geoJsonFile
 	default:
 		return errors.New("unkown data type for writing json")
 	}
+	err := os.MkdirAll(dir, os.ModePerm)
+	if err != nil {
+		if *debug {
+			log.Println("writeNewJSON: error while creating directory", err)
+		}
+		return err
+	}
 	b, err := json.Marshal(i)
 	if err != nil {
 		if *debug {
@@ -95,7 +178,13 @@ func writeNewJSON(i interface{}) error {
 		}
 		return err
 	}
-	err = os.WriteFile(jsonFilePath, b, 0644)
+	if isCache {
+		err = backupJSONFile(jsonFilePath)
+		if err != nil {
+			log.Println("writeNewJSON: error while keeping a backup of", jsonFilePath, err)
+		}
+	}
+	err = writeFileAtomically(jsonFilePath, b)
 	if err != nil {
 		if *debug {
 			log.Println("writeNewJSON: error while writing data json", err)
@@ -105,11 +194,22 @@ func writeNewJSON(i interface{}) error {
 	return nil
 }
 
-func getJson(url string, target interface{}) error {
-	r, err := httpClient.Get(url)
+// getJson decodes the JSON found at url into target, using the http cache with
+// the given maxAge. If the response did not change since the last call, target
+// is left untouched and false is returned, so callers can reuse the data they
+// already parsed before.
+func getJson(ctx context.Context, requestURL string, maxAge time.Duration, target interface{}) (bool, error) {
+	return postJson(ctx, requestURL, nil, maxAge, target)
+}
+
+// postJson works like getJson, but sends form as POST body if it is not nil
+func postJson(ctx context.Context, requestURL string, form url.Values, maxAge time.Duration, target interface{}) (bool, error) {
+	body, isChanged, err := fetchCached(ctx, requestURL, form, maxAge)
 	if err != nil {
-		return err
+		return false, err
+	}
+	if !isChanged {
+		return false, nil
 	}
-	defer r.Body.Close()
-	return json.NewDecoder(r.Body).Decode(target)
+	return true, json.Unmarshal(body, target)
 }
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"html"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// VvrLine is one line serving a VVR stop as given in the linien markup
+type VvrLine struct {
+	Name string
+	// Class is the CSS class of the line, which tells its type or color
+	Class string
+}
+
+var linienSpanRegex = regexp.MustCompile(`(?s)<span([^>]*)>(.*?)</span\s*>`)
+var linienClassRegex = regexp.MustCompile(`class\s*=\s*["']([^"']*)["']`)
+var htmlTagRegex = regexp.MustCompile(`<[^>]*>`)
+
+func cleanLineName(s string) string {
+	s = htmlTagRegex.ReplaceAllString(s, "")
+	s = html.UnescapeString(s)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// parseVvrLinien parses the linien markup of a VVR stop into a sorted list of
+// unique lines. Lines are usually given as span elements, plain text separated
+// by commas or spaces is understood as well.
+func parseVvrLinien(linien string) []VvrLine {
+	var lines []VvrLine
+	known := make(map[string]bool)
+	add := func(line VvrLine) {
+		if line.Name == "" || known[line.Name] {
+			return
+		}
+		known[line.Name] = true
+		lines = append(lines, line)
+	}
+	res := linienSpanRegex.FindAllStringSubmatch(linien, -1)
+	for i := range res {
+		var line VvrLine
+		line.Name = cleanLineName(res[i][2])
+		if class := linienClassRegex.FindStringSubmatch(res[i][1]); class != nil {
+			line.Class = strings.TrimSpace(class[1])
+		}
+		add(line)
+	}
+	if len(res) == 0 {
+		for _, name := range strings.FieldsFunc(cleanLineName(linien), func(r rune) bool {
+			return r == ',' || r == ';' || unicode.IsSpace(r)
+		}) {
+			add(VvrLine{Name: name})
+		}
+	}
+	sort.SliceStable(lines, func(a, b int) bool {
+		return isLineNameLess(lines[a].Name, lines[b].Name)
+	})
+	return lines
+}
+
+// splitLineName splits a line name into chunks of digits and non-digits, e.g. X12a into X, 12, a
+func splitLineName(name string) []string {
+	var chunks []string
+	current := ""
+	for _, r := range name {
+		if current != "" && unicode.IsDigit(r) != unicode.IsDigit([]rune(current)[0]) {
+			chunks = append(chunks, current)
+			current = ""
+		}
+		current += string(r)
+	}
+	if current != "" {
+		chunks = append(chunks, current)
+	}
+	return chunks
+}
+
+// isLineNameLess sorts line names numerically aware: 2 before 10, numbers before letters
+func isLineNameLess(a, b string) bool {
+	chunksA := splitLineName(a)
+	chunksB := splitLineName(b)
+	for i := 0; i < len(chunksA) && i < len(chunksB); i++ {
+		ca, cb := chunksA[i], chunksB[i]
+		if ca == cb {
+			continue
+		}
+		na, errA := strconv.Atoi(ca)
+		nb, errB := strconv.Atoi(cb)
+		switch {
+		case errA == nil && errB == nil:
+			if na != nb {
+				return na < nb
+			}
+			return ca < cb
+		case errA == nil:
+			return true
+		case errB == nil:
+			return false
+		default:
+			return ca < cb
+		}
+	}
+	return len(chunksA) < len(chunksB)
+}
+
+// getLineNames returns the names of the given lines
+func getLineNames(lines []VvrLine) []string {
+	names := make([]string, len(lines))
+	for i := range lines {
+		names[i] = lines[i].Name
+	}
+	return names
+}
+
+// parseRouteRef splits a route_ref value into its lines, sorted and without duplicates
+func parseRouteRef(routeRef string) []string {
+	var lines []string
+	known := make(map[string]bool)
+	for _, line := range strings.Split(routeRef, ";") {
+		line = strings.TrimSpace(line)
+		if line == "" || known[strings.ToUpper(line)] {
+			continue
+		}
+		known[strings.ToUpper(line)] = true
+		lines = append(lines, line)
+	}
+	sort.SliceStable(lines, func(a, b int) bool {
+		return isLineNameLess(lines[a], lines[b])
+	})
+	return lines
+}
+
+// compareRouteRef compares the lines of a route_ref value as a set with the
+// expected lines and returns the missing and the extra lines
+func compareRouteRef(routeRef string, expected []string) ([]string, []string) {
+	var missing, extra []string
+	actual := parseRouteRef(routeRef)
+	isActual := make(map[string]bool)
+	for _, line := range actual {
+		isActual[strings.ToUpper(line)] = true
+	}
+	isExpected := make(map[string]bool)
+	for _, line := range expected {
+		isExpected[strings.ToUpper(line)] = true
+		if !isActual[strings.ToUpper(line)] {
+			missing = append(missing, line)
+		}
+	}
+	for _, line := range actual {
+		if !isExpected[strings.ToUpper(line)] {
+			extra = append(extra, line)
+		}
+	}
+	return missing, extra
+}
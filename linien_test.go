@@ -0,0 +1,96 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseVvrLinien(t *testing.T) {
+	tests := []struct {
+		linien string
+		lines  []VvrLine
+	}{
+		{"", nil},
+		// sorted numerically aware
+		{`<span class="bus">10</span><span class="bus">2</span><span class="bus">1</span>`,
+			[]VvrLine{{"1", "bus"}, {"2", "bus"}, {"10", "bus"}}},
+		// non-numeric lines after the numeric ones
+		{`<span class="express">X1</span> <span class='sev'>SEV</span><span class="night">N3</span><span class="ruf">RUF</span><span class="bus">3</span>`,
+			[]VvrLine{{"3", "bus"}, {"N3", "night"}, {"RUF", "ruf"}, {"SEV", "sev"}, {"X1", "express"}}},
+		// markup and entities inside a span, duplicates keep the first class
+		{`<span class=" bus "> <b>10</b>&nbsp;</span><span class="sev">10</span><span>5</span >`,
+			[]VvrLine{{"5", ""}, {"10", "bus"}}},
+		// plain text fallback
+		{"10, 2; X1 1", []VvrLine{{"1", ""}, {"2", ""}, {"10", ""}, {"X1", ""}}},
+		{"SEV", []VvrLine{{"SEV", ""}}},
+	}
+	for _, test := range tests {
+		if lines := parseVvrLinien(test.linien); !reflect.DeepEqual(lines, test.lines) {
+			t.Errorf("parseVvrLinien(%q) = %v, want %v", test.linien, lines, test.lines)
+		}
+	}
+}
+
+func TestSplitLineName(t *testing.T) {
+	tests := []struct {
+		name   string
+		chunks []string
+	}{
+		{"", nil},
+		{"10", []string{"10"}},
+		{"SEV", []string{"SEV"}},
+		{"X12a", []string{"X", "12", "a"}},
+		{"N3", []string{"N", "3"}},
+	}
+	for _, test := range tests {
+		if chunks := splitLineName(test.name); !reflect.DeepEqual(chunks, test.chunks) {
+			t.Errorf("splitLineName(%q) = %v, want %v", test.name, chunks, test.chunks)
+		}
+	}
+}
+
+func TestIsLineNameLess(t *testing.T) {
+	tests := []struct {
+		a, b string
+		less bool
+	}{
+		{"2", "10", true},
+		{"10", "2", false},
+		{"10", "X1", true},
+		{"N3", "3", false},
+		{"X2", "X10", true},
+		{"X10", "X2", false},
+		{"N3", "RUF", true},
+		{"RUF", "SEV", true},
+		{"1", "1a", true},
+		{"1a", "1", false},
+		{"01", "1", true},
+		{"5", "5", false},
+	}
+	for _, test := range tests {
+		if less := isLineNameLess(test.a, test.b); less != test.less {
+			t.Errorf("isLineNameLess(%q, %q) = %v, want %v", test.a, test.b, less, test.less)
+		}
+	}
+}
+
+func TestCompareRouteRef(t *testing.T) {
+	tests := []struct {
+		routeRef string
+		expected []string
+		missing  []string
+		extra    []string
+	}{
+		{"1;2;10", []string{"1", "2", "10"}, nil, nil},
+		// order, spaces and case do not matter
+		{"10; 2;x1", []string{"2", "10", "X1", "SEV"}, []string{"SEV"}, nil},
+		{"1;RUF;2;1", []string{"1"}, nil, []string{"2", "RUF"}},
+		{"", []string{"3"}, []string{"3"}, nil},
+	}
+	for _, test := range tests {
+		missing, extra := compareRouteRef(test.routeRef, test.expected)
+		if !reflect.DeepEqual(missing, test.missing) || !reflect.DeepEqual(extra, test.extra) {
+			t.Errorf("compareRouteRef(%q, %v) = %v, %v, want %v, %v", test.routeRef, test.expected, missing, extra, test.missing, test.extra)
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// errLockHeld is returned by lockFileExclusively if another process holds the lock
+var errLockHeld = errors.New("lock is held by another process")
+
+// errLockUnsupported is returned by lockFileExclusively if the OS or the file
+// system cannot lock files, only the PID in the lock file is checked then
+var errLockUnsupported = errors.New("file locking is not supported")
+
+// LockInfo is stored in the lock file to tell who holds it
+type LockInfo struct {
+	PID       int       `json:"pid"`
+	Hostname  string    `json:"hostname"`
+	StartTime time.Time `json:"start_time"`
+	Command   string    `json:"command"`
+}
+
+func (info LockInfo) String() string {
+	return fmt.Sprintf("PID %d on %s since %s (%s)", info.PID, info.Hostname, info.StartTime.Format(time.RFC3339), info.Command)
+}
+
+// isAlive reports whether the process holding the lock is still running. The
+// processes of other hosts cannot be checked and are considered alive.
+func (info LockInfo) isAlive() bool {
+	hostname, err := os.Hostname()
+	if err != nil || hostname != info.Hostname {
+		return true
+	}
+	return isProcessAlive(info.PID)
+}
+
+// heldLocks are the open lock files of this process, the OS lock lasts as long
+// as the file is open
+var heldLocks = make(map[string]*os.File)
+var heldLocksMutex sync.Mutex
+
+func readLockInfo(f *os.File) (*LockInfo, error) {
+	b, err := io.ReadAll(io.NewSectionReader(f, 0, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, nil
+	}
+	var info LockInfo
+	err = json.Unmarshal(b, &info)
+	if err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// openLockFile opens lf and locks it. It retries if lf was replaced while
+// waiting, because the lock of a removed file protects nothing.
+func openLockFile(lf string) (*os.File, error) {
+	for i := 0; i < 10; i++ {
+		f, err := os.OpenFile(lf, os.O_RDWR|os.O_CREATE, 0644)
+		if err != nil {
+			return nil, err
+		}
+		err = lockFileExclusively(f)
+		if err != nil && !errors.Is(err, errLockUnsupported) {
+			f.Close()
+			return nil, err
+		}
+		fiOpened, statErr := f.Stat()
+		fiPath, pathErr := os.Stat(lf)
+		if statErr == nil && pathErr == nil && os.SameFile(fiOpened, fiPath) {
+			return f, err
+		}
+		f.Close()
+	}
+	return nil, errors.New("lock file " + lf + " keeps changing")
+}
+
+// createLockFile creates the lock file, so we do not run this process two
+// times. The lock file contains PID, hostname and start time of the holder and
+// is locked by the OS as long as the holder runs, a lock file left behind by a
+// crashed run is reclaimed.
+func createLockFile(lf string) error {
+	f, err := openLockFile(lf)
+	if errors.Is(err, errLockHeld) {
+		f, openErr := os.Open(lf)
+		if openErr != nil {
+			return fmt.Errorf("lock file %s is held by another process", lf)
+		}
+		defer f.Close()
+		info, _ := readLockInfo(f)
+		if info == nil {
+			return fmt.Errorf("lock file %s is held by another process", lf)
+		}
+		return fmt.Errorf("lock file %s is held by %s", lf, info)
+	}
+	isLockUnsupported := errors.Is(err, errLockUnsupported)
+	if err != nil && !isLockUnsupported {
+		return err
+	}
+	info, err := readLockInfo(f)
+	if err != nil {
+		log.Printf("createLockFile: ignoring unreadable lock file %s: %v\n", lf, err)
+	}
+	if info != nil {
+		if isLockUnsupported && info.isAlive() {
+			f.Close()
+			return fmt.Errorf("lock file %s is held by %s", lf, info)
+		}
+		log.Printf("createLockFile: reclaiming stale lock file %s of %s\n", lf, info)
+	} else if *verbose {
+		log.Printf("no lockfile %s present\n", lf)
+	}
+
+	hostname, _ := os.Hostname()
+	b, err := json.Marshal(LockInfo{
+		PID:       os.Getpid(),
+		Hostname:  hostname,
+		StartTime: time.Now(),
+		Command:   strings.Join(os.Args, " "),
+	})
+	if err == nil {
+		err = f.Truncate(0)
+	}
+	if err == nil {
+		_, err = f.WriteAt(b, 0)
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if err != nil {
+		if *debug {
+			log.Println("createLockFile: error while writing lock file")
+		}
+		f.Close()
+		os.Remove(lf)
+		return err
+	}
+	heldLocksMutex.Lock()
+	heldLocks[lf] = f
+	heldLocksMutex.Unlock()
+	return nil
+}
+
+// removeLockFile removes a lock file created by this process
+func removeLockFile(lf string) {
+	if *verbose {
+		log.Printf("removeLockFile: trying to delete %s\n", lf)
+	}
+	heldLocksMutex.Lock()
+	defer heldLocksMutex.Unlock()
+	f, exists := heldLocks[lf]
+	if !exists {
+		log.Printf("removeLockFile: lock file %s is not held by this process\n", lf)
+		return
+	}
+	delete(heldLocks, lf)
+	// remove before closing, so nobody can lock the old file in between
+	err := os.Remove(lf)
+	if err != nil {
+		log.Printf("removeLockFile: error while removing lock file %s: %v\n", lf, err)
+	}
+	err = f.Close()
+	if err != nil {
+		log.Printf("removeLockFile: error while closing lock file %s: %v\n", lf, err)
+	}
+}
+
+// getLockHolder returns who holds the lock file, nil if nobody does
+func getLockHolder(lf string) *LockInfo {
+	heldLocksMutex.Lock()
+	_, isHeldByUs := heldLocks[lf]
+	heldLocksMutex.Unlock()
+	f, err := os.Open(lf)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	info, _ := readLockInfo(f)
+	if info == nil || isHeldByUs {
+		return info
+	}
+	err = lockFileShared(f)
+	if errors.Is(err, errLockHeld) {
+		return info
+	}
+	if errors.Is(err, errLockUnsupported) && info.isAlive() {
+		return info
+	}
+	// a stale lock file, closing f releases our lock again
+	return nil
+}
+
+// removeLockFilesOnSignal removes the lock files of this process and exits on
+// SIGINT or SIGTERM
+func removeLockFilesOnSignal() {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		sig := <-c
+		log.Printf("received %s, exiting\n", sig)
+		heldLocksMutex.Lock()
+		var lockFiles []string
+		for lf := range heldLocks {
+			lockFiles = append(lockFiles, lf)
+		}
+		heldLocksMutex.Unlock()
+		for _, lf := range lockFiles {
+			removeLockFile(lf)
+		}
+		os.Exit(1)
+	}()
+}
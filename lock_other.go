@@ -0,0 +1,20 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+
+package main
+
+import "os"
+
+// lockFileExclusively cannot lock files on this OS, only the PID in the lock file is checked
+func lockFileExclusively(f *os.File) error {
+	return errLockUnsupported
+}
+
+func lockFileShared(f *os.File) error {
+	return errLockUnsupported
+}
+
+// isProcessAlive cannot check other processes on this OS, so they are
+// considered alive and a stale lock file has to be removed by hand
+func isProcessAlive(pid int) bool {
+	return true
+}
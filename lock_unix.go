@@ -0,0 +1,36 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package main
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+func flock(f *os.File, how int) error {
+	err := syscall.Flock(int(f.Fd()), how|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		return errLockHeld
+	}
+	if errors.Is(err, syscall.ENOLCK) || errors.Is(err, syscall.EOPNOTSUPP) || errors.Is(err, syscall.EINVAL) {
+		return errLockUnsupported
+	}
+	return err
+}
+
+// lockFileExclusively locks f without waiting, the lock is released when f is closed
+func lockFileExclusively(f *os.File) error {
+	return flock(f, syscall.LOCK_EX)
+}
+
+// lockFileShared tries to lock f shared without waiting, to find out whether
+// anybody holds an exclusive lock
+func lockFileShared(f *os.File) error {
+	return flock(f, syscall.LOCK_SH)
+}
+
+func isProcessAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
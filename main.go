@@ -1,10 +1,12 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"html"
 	"log"
-	"net/url"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -16,233 +18,136 @@ func main() {
 	defer printElapsedTime(start)
 	log.SetOutput(os.Stdout)
 	log.SetFlags(log.LstdFlags | log.Lshortfile)
-	handleFlags()
 
-	// check if lock file exists and exit, so we do not run this process two times
-	if _, err := os.Stat(lockFile); os.IsNotExist(err) {
-		if *verbose {
-			log.Printf("no lockfile %s present\n", lockFile)
-		}
-	} else {
-		fmt.Printf("abort: lock file exists %s\n", lockFile)
-		os.Exit(1)
+	// the first argument is the command, run everything if it is omitted
+	command := "run"
+	args := os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command = args[0]
+		args = args[1:]
 	}
-
-	// create lock file and delete it on exit of main
-	err := os.WriteFile(lockFile, nil, 0644)
-	if err != nil {
-		if *debug {
-			log.Println("main: error while writing lock file")
-		}
-		panic(err)
+	// the daemon shuts down by itself on signals
+	if command != "daemon" {
+		removeLockFilesOnSignal()
 	}
-	defer removeLockFile(lockFile)
-
-	if *verbose {
-		log.Println("reading data json file into memory")
+	var err error
+	switch command {
+	case "run":
+		err = runCommand(args)
+	case "fetch":
+		err = fetchCommand(args)
+	case "match":
+		err = matchCommand(args)
+	case "report":
+		err = reportCommand(args)
+	case "explain":
+		err = explainCommand(args)
+	case "daemon":
+		err = daemonCommand(args)
+	case "serve":
+		err = serveCommand(args)
+	default:
+		printUsage()
+		os.Exit(2)
 	}
-	var oldVvr VvrData
-	err = readCurrentJSON(&oldVvr)
 	if err != nil {
-		removeLockFile(lockFile)
-		panic(err)
+		log.Println(command, "failed:", err)
+		printElapsedTime(start)
+		os.Exit(1)
 	}
+}
 
-	// get the VVR data
-	lenSearchWords := len(alphabet) * len(alphabet)
-	searchWords := make([]string, lenSearchWords)
-	for i := 0; i < len(alphabet); i++ {
-		for k := 0; k < len(alphabet); k++ {
-			searchWords[i*len(alphabet)+k] = alphabet[i] + alphabet[k]
-		}
-	}
-	var newVvr VvrData
-	for i := 0; i < len(searchWords); i++ {
-		var newVvrCity VvrCity
-		var newResult []VvrBusStop
-		isNewApiCallNeeded := false
-		oldVvrCity := getCityResultFromData(searchWords[i], oldVvr)
-		if oldVvrCity != nil {
-			if *debug {
-				log.Printf("found old result for %s, checking for timestamp %s\n", oldVvrCity.SearchWord, oldVvrCity.ResultTimeStamp)
-			}
-			cacheTime := time.Now().Add(-1 * cacheTimeVvrInHours * time.Hour)
-			if oldVvrCity.ResultTimeStamp.Before(cacheTime) {
-				if *debug {
-					log.Printf("data in cache is older than %d hours, trying to get fresh data\n", cacheTimeVvrInHours)
-				}
-				isNewApiCallNeeded = true
-			}
-		} else {
-			isNewApiCallNeeded = true
-		}
-		if isNewApiCallNeeded {
-			getURL := vvrSearchURL + url.QueryEscape(searchWords[i])
-			err = getJson(getURL, &newResult)
-			if err != nil {
-				log.Println("error getting http json for", getURL)
-				log.Println("error is", err)
-				if oldVvrCity != nil {
-					log.Printf("reusing old cache data for %s due to the GET error\n", searchWords[i])
-					newVvr.CityResults = append(newVvr.CityResults, *oldVvrCity)
-				}
-				continue
-			}
-			newVvrCity.SearchWord = searchWords[i]
-			newVvrCity.ResultTimeStamp = time.Now()
-			newVvrCity.Result = newResult
-			newVvr.CityResults = append(newVvr.CityResults, newVvrCity)
-		} else {
-			newVvr.CityResults = append(newVvr.CityResults, *oldVvrCity)
-		}
-	}
-	err = writeNewJSON(newVvr)
+// fetchData updates the cached VVR and OSM data and returns it, cached data
+// younger than the cache times is used without any request
+func fetchData(ctx context.Context) (VvrData, OverpassData, error) {
+	newVvr, _, err := fetchVvrData(ctx, cacheTimeVvrInHours*time.Hour)
 	if err != nil {
-		log.Printf("error writing json with VVR data: %v\n", err)
+		return VvrData{}, OverpassData{}, err
 	}
-	// TODO get all city names from newVvr
-	var extractedCities []string
-	for i := 0; i < len(newVvr.CityResults); i++ {
-		for k := 0; k < len(newVvr.CityResults[i].Result); k++ {
-			bussi := newVvr.CityResults[i].Result[k].Value
-			if strings.Contains(bussi, ",") {
-				bussiCity := strings.Split(bussi, ",")[0]
-				isCityKnown := false
-				for n := 0; n < len(extractedCities); n++ {
-					if extractedCities[n] == bussiCity {
-						isCityKnown = true
-						break
-					}
-				}
-				if !isCityKnown {
-					extractedCities = append(extractedCities, bussiCity)
-				}
-			}
-		}
+	newOverpassData, _, err := fetchOverpassData(ctx, cacheTimeOverpassInHours*time.Hour)
+	if err != nil {
+		return VvrData{}, OverpassData{}, err
 	}
+	return newVvr, newOverpassData, nil
+}
+
+// matchData matches the VVR data with the OSM data, checks the matched OSM
+// objects and returns the result to be rendered
+func matchData(newVvr VvrData, newOverpassData OverpassData) TemplateData {
+	extractedCities := extractCities(newVvr)
 	if *verbose {
 		log.Println("extractedCities:", extractedCities, len(extractedCities))
 	}
-	// get OSM data
-	overpassQuery := overpassURL + overpassQueryPrefix + overpassSearchArea + overpassQuerySuffix
-	if *verbose {
-		log.Println("overpassQuery:", overpassQuery)
-	}
-	var oldOverpassData OverpassData
-	err = readCurrentJSON(&oldOverpassData)
-	if err != nil {
-		removeLockFile(lockFile)
-		panic(err)
-	}
-	var newOverpassData OverpassData
-	cacheTime := time.Now().Add(-1 * cacheTimeOverpassInHours * time.Hour)
-	isWriteOverpassJson := false
-	if oldOverpassData.Osm3S.TimestampOsmBase.Before(cacheTime) {
-		err = getJson(overpassQuery, &newOverpassData)
-		if err != nil {
-			log.Println("error getting http json for", overpassQuery)
-			log.Println("error is", err)
-			log.Println("reusing old overpass cache data due to the GET error")
-			newOverpassData = oldOverpassData
-		}
-		isWriteOverpassJson = true
-	} else {
-		if *verbose {
-			log.Println("reusing old overpass data from cache, data is not older than hours:", cacheTimeOverpassInHours)
-		}
-		newOverpassData = oldOverpassData
-	}
-	if isWriteOverpassJson {
-		err = writeNewJSON(newOverpassData)
-		if err != nil {
-			log.Printf("error writing json with VVR data: %v\n", err)
-		}
+	// count the ignored objects per operator
+	ignoredOperators := make(map[string]int)
+	for operator := range ignoreBusStopsWithOperators {
+		ignoredOperators[operator] = 0
 	}
 
-	totalOsmElements := len(newOverpassData.Elements)
+	// bus routes are only used to check the lines of the stops
+	busRoutes, stopElements := takeMatchingElements(newOverpassData.Elements, isBusRoute)
+	routeIndex := buildRouteIndex(busRoutes)
+	totalOsmElements := len(stopElements)
 	if *debug {
-		log.Println("newOverpassData.Elements before matching:", len(newOverpassData.Elements))
+		log.Println("bus routes:", len(busRoutes))
+		log.Println("stopElements before matching:", len(stopElements))
 	}
 	// match VVR data with OSM Elements
 	if *verbose {
 		log.Println("matching VVR data with OSM Elements")
 	}
-	var mbs []MatchedBusStop
-	insaneLoops := 0
-	for i := 0; i < len(newVvr.CityResults); i++ {
-		for k := 0; k < len(newVvr.CityResults[i].Result); k++ {
-			oneBusStop := newVvr.CityResults[i].Result[k]
-			var oneMatch MatchedBusStop
-			oneMatch.Name = oneBusStop.Value
-			oneMatch.Linien = oneBusStop.Linien
-			oneMatch.VvrID = oneBusStop.ID
-			// use VVR ID to remove duplicate VVR entities
-			vvrIsDuplicate := false
-			for p := 0; p < len(mbs); p++ {
-				if mbs[p].VvrID == oneMatch.VvrID {
-					vvrIsDuplicate = true
-					break
-				}
-			}
-			vvrIsSpecialDestination := false
-			for specDest := 0; specDest < len(ignoreVvrStops); specDest++ {
-				if oneMatch.Name == ignoreVvrStops[specDest] {
-					vvrIsSpecialDestination = true
-					break
-				}
-			}
-			if !vvrIsDuplicate && !vvrIsSpecialDestination {
-				oneMatch.City = newVvr.CityResults[i].SearchWord
-				var removeOsmElementsByID []int64
-				for m := 0; m < len(newOverpassData.Elements); m++ {
-					if doesOsmElementMatchVvrElement(newOverpassData.Elements[m], oneMatch.Name, extractedCities) {
-						oneMatch.Elements = append(oneMatch.Elements, newOverpassData.Elements[m])
-						removeOsmElementsByID = append(removeOsmElementsByID, newOverpassData.Elements[m].ID)
-					}
-					insaneLoops++
-					// do not break here because one VVR element can match multiple OSM objects
-				}
-				// remove the elements we already matched
-				for n := 0; n < len(removeOsmElementsByID); n++ {
-					toDeleteOsmId := removeOsmElementsByID[n]
-					for p := 0; p < len(newOverpassData.Elements); p++ {
-						if newOverpassData.Elements[p].ID == toDeleteOsmId {
-							newOverpassData.Elements = append(newOverpassData.Elements[:p], newOverpassData.Elements[p+1:]...)
-							continue
-						}
-					}
-				}
-				mbs = append(mbs, oneMatch)
-			}
-		}
-	}
-	remainingOsmElements := len(newOverpassData.Elements)
+	mbs, remainingElements := matchVvrWithOsm(newVvr, stopElements, extractedCities)
+	remainingOsmElements := len(remainingElements)
 	if *verbose {
-		log.Println("insane looping finished:", insaneLoops)
-		log.Println("newOverpassData.Elements left after matching:", len(newOverpassData.Elements))
+		log.Println("stopElements left after matching:", remainingOsmElements)
 	}
 
 	// append remaining OSM elements, which couldn't be matched
-	for i := 0; i < len(newOverpassData.Elements); i++ {
-		index := doesNameExistAlreadyInArray(mbs, newOverpassData.Elements[i].Tags.Name)
+	vvrStops := make([]MatchedBusStop, len(mbs))
+	copy(vvrStops, mbs)
+	// unnamed elements get a row of their own each
+	for i := 0; i < len(remainingElements); i++ {
+		index := -1
+		if remainingElements[i].Tags.Name() != "" {
+			index = doesNameExistAlreadyInArray(mbs, remainingElements[i].Tags.Name())
+		}
 		if index >= 0 {
-			mbs[index].Elements = append(mbs[index].Elements, newOverpassData.Elements[i])
+			mbs[index].Elements = append(mbs[index].Elements, remainingElements[i])
 		} else {
 			var notInVvrButInOsm MatchedBusStop
-			notInVvrButInOsm.Name = newOverpassData.Elements[i].Tags.Name
-			notInVvrButInOsm.Elements = append(notInVvrButInOsm.Elements, newOverpassData.Elements[i])
+			notInVvrButInOsm.Name = remainingElements[i].Tags.Name()
+			notInVvrButInOsm.Elements = append(notInVvrButInOsm.Elements, remainingElements[i])
 			mbs = append(mbs, notInVvrButInOsm)
 		}
 	}
+	// group the report by municipality, rows without known municipality last
+	for i := 0; i < len(mbs); i++ {
+		if mbs[i].VvrID == "" {
+			mbs[i].City = getCityOfOsmRow(mbs[i], extractedCities, vvrStops, routeIndex)
+		}
+	}
+	sort.SliceStable(mbs, func(a, b int) bool {
+		if (mbs[a].City == "") != (mbs[b].City == "") {
+			return mbs[b].City == ""
+		}
+		return mbs[a].City < mbs[b].City
+	})
 
 	vvrBusStopSum := 0
 	remainingVvrStops := 0
 	osmStopsNoName := 0
 	warningsSum := 0
+	orphanPlatforms := 0
+	orphanStopPositions := 0
+	stopAreaIndex := buildStopAreaIndex(stopElements)
+	var auditedStops []MatchedBusStop
 	result := make([]MatchResult, len(mbs))
 	for i := 0; i < len(mbs); i++ {
+		warningsBefore := warningsSum
 		result[i].ID = i + 1
 		result[i].VvrID = mbs[i].VvrID
+		result[i].DHID = mbs[i].DHID
+		result[i].Lines = strings.Join(getLineNames(mbs[i].Lines), ", ")
 		result[i].IsInOSM = false
 		if len(mbs[i].Elements) > 0 {
 			result[i].IsInOSM = true
@@ -255,14 +160,12 @@ func main() {
 		result[i].NrPlatforms = 0
 		result[i].NrStopPositions = 0
 		result[i].Name = mbs[i].Name
+		result[i].City = mbs[i].City
 		result[i].IsIgnored = false
 		result[i].OsmReference = ""
 		// we have a match from VVR which is not in OSM, so let's check the bus lines
 		if result[i].IsInVVR && !result[i].IsInOSM {
-			busLines, err := convertLinienToRouteRef(mbs[i].Linien)
-			if err != nil {
-				log.Println("error while getting bus lines for", result[i].Name, err)
-			}
+			busLines := strings.Join(getLineNames(mbs[i].Lines), ";")
 			if *debug {
 				log.Println(result[i].Name, "is in VVR, but in OSM, so these are the bus lines:", busLines)
 			}
@@ -270,7 +173,7 @@ func main() {
 				result[i].OsmReference = result[i].OsmReference + "- no buslines are attached to this bus stop, currently not used by VVR"
 				warningsSum++
 			} else {
-				result[i].OsmReference = result[i].OsmReference + "- bus stop is used for bus lines " + busLines
+				result[i].OsmReference = result[i].OsmReference + "- bus stop is used for bus lines " + html.EscapeString(busLines)
 				warningsSum++
 				// fmt.Printf("|-\n| " + result[i].Name + " || " + busLines + " || \n")
 			}
@@ -278,92 +181,184 @@ func main() {
 		for k := 0; k < len(mbs[i].Elements); k++ {
 			object := mbs[i].Elements[k]
 			object_id := strconv.FormatInt(object.ID, 10)
+			if lat, lon, ok := object.Coordinates(); ok && result[i].Lat == 0 && result[i].Lon == 0 {
+				result[i].Lat = lat
+				result[i].Lon = lon
+			}
 			objectURL := "http://osm.org/" + object.Type + "/" + object_id
 			// OSM Reference column filling Start
 			josm_link := "<a href=\"http://127.0.0.1:8111/load_object?new_layer=false&objects=" + string(object.Type[0]) + object_id + "\" target=\"hiddenIframe\" title=\"edit in JOSM\">(j)</a>"
 			result[i].OsmReference = result[i].OsmReference + "<p><a href=\"" + objectURL + "\">" + object.Type + " " + object_id + "</a> " + josm_link
 			// ignore certain bus stops having a known operator
-			value, exists := ignoreBusStopsWithOperators[object.Tags.Operator]
+			value, exists := ignoredOperators[object.Tags.Operator()]
 			if exists {
 				value++
-				ignoreBusStopsWithOperators[object.Tags.Operator] = value
+				ignoredOperators[object.Tags.Operator()] = value
 				if *debug {
-					log.Println("operator", object.Tags.Operator, "shall be ignored for object", objectURL)
+					log.Println("operator", object.Tags.Operator(), "shall be ignored for object", objectURL)
 				}
-				result[i].OsmReference = result[i].OsmReference + " (Operator is " + object.Tags.Operator + ")</p>"
+				result[i].OsmReference = result[i].OsmReference + " (Operator is " + html.EscapeString(object.Tags.Operator()) + ")</p>"
 				result[i].IsIgnored = true
 				// skip further processing for this bus stop because it is not VVR but a different operator
 				continue
 			}
-			if object.Type != "relation" && (object.Tags.PublicTransport != "stop_position" || object.Tags.Highway == "bus_stop") {
-				if object.Tags.Network == "" {
+			if object.Type != "relation" && (object.Tags.PublicTransport() != "stop_position" || object.Tags.Highway() == "bus_stop") {
+				if object.Tags.Network() == "" {
 					result[i].OsmReference = result[i].OsmReference + "<br />- " + warning_network_tag_missing
 					warningsSum++
-				} else if object.Tags.Network != tag_network {
-					result[i].OsmReference = result[i].OsmReference + "<br />- " + warning_network_tag_not_correct + ". " + object.Tags.Network + " instead of network=" + tag_network
+				} else if object.Tags.Network() != tag_network {
+					result[i].OsmReference = result[i].OsmReference + "<br />- " + warning_network_tag_not_correct + ". " + html.EscapeString(object.Tags.Network()) + " instead of network=" + tag_network
 					warningsSum++
 				}
-				if object.Tags.NetworkGuid == "" {
+				if object.Tags.NetworkGuid() == "" {
 					result[i].OsmReference = result[i].OsmReference + "<br />- " + warning_network_guid_tag_missing
 					warningsSum++
-				} else if object.Tags.NetworkGuid != tag_network_guid {
-					result[i].OsmReference = result[i].OsmReference + "<br />- " + warning_network_guid_tag_not_correct + ". " + object.Tags.NetworkGuid + " instead of network:guid=" + tag_network_guid
+				} else if object.Tags.NetworkGuid() != tag_network_guid {
+					result[i].OsmReference = result[i].OsmReference + "<br />- " + warning_network_guid_tag_not_correct + ". " + html.EscapeString(object.Tags.NetworkGuid()) + " instead of network:guid=" + tag_network_guid
 					warningsSum++
 				}
-				if object.Tags.NetworkShort == "" {
+				if object.Tags.NetworkShort() == "" {
 					result[i].OsmReference = result[i].OsmReference + "<br />- " + warning_network_short_tag_missing
 					warningsSum++
-				} else if object.Tags.NetworkShort != tag_network_short {
-					result[i].OsmReference = result[i].OsmReference + "<br />- " + warning_network_short_tag_not_correct + ". " + object.Tags.NetworkShort + " instead of network:short" + tag_network_short
+				} else if object.Tags.NetworkShort() != tag_network_short {
+					result[i].OsmReference = result[i].OsmReference + "<br />- " + warning_network_short_tag_not_correct + ". " + html.EscapeString(object.Tags.NetworkShort()) + " instead of network:short" + tag_network_short
 					warningsSum++
 				}
 			}
 			// check route_ref for platforms only
-			targetRouteRef, err := convertLinienToRouteRef(mbs[i].Linien)
-			if err != nil {
-				log.Println("convertLinienToRouteRef failed with error:", err)
-			}
-			if object.Tags.PublicTransport == "platform" && object.Tags.RouteRef == "" && targetRouteRef != "" {
-				result[i].OsmReference = result[i].OsmReference + "<br />- route_ref missing:<br><code>route_ref=" + targetRouteRef + "</code>"
+			targetRouteRef := strings.Join(getLineNames(mbs[i].Lines), ";")
+			if object.Tags.PublicTransport() == "platform" && object.Tags.RouteRef() == "" && targetRouteRef != "" {
+				result[i].OsmReference = result[i].OsmReference + "<br />- route_ref missing:<br><code>route_ref=" + html.EscapeString(targetRouteRef) + "</code>"
 				warningsSum++
 			}
-			if object.Tags.PublicTransport == "platform" && object.Tags.RouteRef != "" && targetRouteRef != "" && object.Tags.RouteRef != targetRouteRef {
-				result[i].OsmReference = result[i].OsmReference + "<br />- existing <code>route_ref=" + object.Tags.RouteRef + "</code> does not match calculated <code>route_ref=" + targetRouteRef + "</code>"
-				warningsSum++
+			if object.Tags.PublicTransport() == "platform" && object.Tags.RouteRef() != "" && targetRouteRef != "" {
+				missingLines, extraLines := compareRouteRef(object.Tags.RouteRef(), getLineNames(mbs[i].Lines))
+				if config.AllowOtherLinesInRouteRef {
+					extraLines = nil
+				}
+				if len(missingLines) > 0 {
+					result[i].OsmReference = result[i].OsmReference + "<br />- existing <code>route_ref=" + html.EscapeString(object.Tags.RouteRef()) + "</code> " + warning_route_ref_lines_missing + ": " + html.EscapeString(strings.Join(missingLines, ", "))
+					warningsSum++
+				}
+				if len(extraLines) > 0 {
+					result[i].OsmReference = result[i].OsmReference + "<br />- existing <code>route_ref=" + html.EscapeString(object.Tags.RouteRef()) + "</code> " + warning_route_ref_lines_extra + ": " + html.EscapeString(strings.Join(extraLines, ", "))
+					warningsSum++
+				}
+				if len(missingLines) > 0 || len(extraLines) > 0 {
+					suggestedRouteRef := targetRouteRef
+					if config.AllowOtherLinesInRouteRef {
+						suggestedRouteRef = strings.Join(parseRouteRef(object.Tags.RouteRef()+";"+targetRouteRef), ";")
+					}
+					result[i].OsmReference = result[i].OsmReference + "<br /><code>route_ref=" + html.EscapeString(suggestedRouteRef) + "</code>"
+				}
 			}
 			// check operator
-			if object.Tags.Operator == "" {
+			if object.Tags.Operator() == "" {
 				result[i].OsmReference = result[i].OsmReference + "<br />- " + warning_operator_tag_missing + warning_operator_might_be_vvr
 				warningsSum++
-			} else if object.Tags.Operator != tag_operator {
-				result[i].OsmReference = result[i].OsmReference + "<br />- " + warning_operator_tag_not_correct + ". " + object.Tags.Operator + " instead of operator=" + tag_operator
+			} else if object.Tags.Operator() != tag_operator {
+				result[i].OsmReference = result[i].OsmReference + "<br />- " + warning_operator_tag_not_correct + ". " + html.EscapeString(object.Tags.Operator()) + " instead of operator=" + tag_operator
 				warningsSum++
 			}
+			// check ref:IFOPT against the DHID of the VVR stop
+			if mbs[i].DHID != "" && object.Tags.PublicTransport() != "" {
+				if object.Tags.RefIFOPT() == "" {
+					result[i].OsmReference = result[i].OsmReference + "<br />- " + warning_ifopt_tag_missing + ", DHID of the stop is <code>" + html.EscapeString(mbs[i].DHID) + "</code>"
+					warningsSum++
+				} else if !doesIfoptBelongToDhid(object.Tags.RefIFOPT(), mbs[i].DHID) {
+					result[i].OsmReference = result[i].OsmReference + "<br />- " + warning_ifopt_tag_not_correct + ". <code>ref:IFOPT=" + html.EscapeString(object.Tags.RefIFOPT()) + "</code> instead of <code>" + html.EscapeString(mbs[i].DHID) + "</code>"
+					warningsSum++
+				}
+			}
+			// list missing platform attributes
+			if config.AmenityAudit.Enabled && result[i].IsInVVR && object.Tags.PublicTransport() == "platform" {
+				missingAmenityTags := getMissingAmenityTags(object)
+				if len(missingAmenityTags) > 0 {
+					result[i].OsmReference = result[i].OsmReference + "<br />- attributes missing: " + strings.Join(missingAmenityTags, ", ")
+				}
+			}
+			// suggest a name for unnamed objects
+			if object.Tags.Name() == "" && !result[i].IsInVVR {
+				suggestion := suggestNameForUnnamed(object, vvrStops, routeIndex)
+				if suggestion != nil {
+					result[i].OsmReference = result[i].OsmReference + "<br />- name missing, " + getNameSuggestionText(object, *suggestion)
+				}
+			}
+			// check the municipality the object is tagged to be in
+			if result[i].IsInVVR {
+				osmMunicipality := getMunicipalityOfElement(object)
+				if osmMunicipality != "" && !isSameMunicipality(osmMunicipality, parseVvrStopName(mbs[i].Name)) {
+					result[i].OsmReference = result[i].OsmReference + "<br />- " + warning_municipality_differs + ": " + html.EscapeString(osmMunicipality) + " instead of " + html.EscapeString(mbs[i].City)
+					warningsSum++
+				}
+			}
+			// check legacy bus stops
+			if object.Tags.Highway() == "bus_stop" && object.Tags.PublicTransport() != "platform" {
+				result[i].OsmReference = result[i].OsmReference + "<br />- " + warning_bus_stop_not_platform
+				warningsSum++
+			}
+			// check membership in a stop_area relation
+			if stopAreaIndex.isOrphan(object) {
+				result[i].OsmReference = result[i].OsmReference + "<br />- " + warning_stop_area_orphan
+				warningsSum++
+				if object.Tags.PublicTransport() == "platform" {
+					orphanPlatforms++
+				} else {
+					orphanStopPositions++
+				}
+			}
 			result[i].OsmReference = result[i].OsmReference + "</p>"
 			// OSM Reference column filling End
-			if object.Tags.Highway == "bus_stop" {
+			if object.Tags.Highway() == "bus_stop" {
 				result[i].NrBusStops++
 			}
-			if object.Tags.PublicTransport == "stop_position" {
+			if object.Tags.PublicTransport() == "stop_position" {
 				result[i].NrStopPositions++
 			}
-			if object.Tags.PublicTransport == "platform" {
+			if object.Tags.PublicTransport() == "platform" {
 				result[i].NrPlatforms++
 			}
-			if object.Tags.Name == "" {
+			if object.Tags.Name() == "" {
 				osmStopsNoName++
 			}
 		}
 		if result[i].IsInVVR && len(mbs[i].Elements) == 0 {
 			remainingVvrStops++
 		}
+		// check the stop_area relations of matched VVR stops
+		if result[i].IsInVVR && result[i].IsInOSM && !result[i].IsIgnored {
+			auditedStops = append(auditedStops, mbs[i])
+			stopAreaWarnings := checkStopAreaOfStop(mbs[i], stopAreaIndex)
+			if len(stopAreaWarnings) > 0 {
+				result[i].OsmReference = result[i].OsmReference + "<p>stop_area:<br />- " + strings.Join(stopAreaWarnings, "<br />- ") + "</p>"
+				warningsSum += len(stopAreaWarnings)
+			}
+			pairingWarnings := checkPlatformPairing(mbs[i])
+			if len(pairingWarnings) > 0 {
+				result[i].OsmReference = result[i].OsmReference + "<p>platforms:<br />- " + strings.Join(pairingWarnings, "<br />- ") + "</p>"
+				warningsSum += len(pairingWarnings)
+			}
+			nameWarnings := checkNameConsistency(mbs[i], extractedCities)
+			if len(nameWarnings) > 0 {
+				result[i].OsmReference = result[i].OsmReference + "<p>names:<br />- " + strings.Join(nameWarnings, "<br />- ") + "</p>"
+				warningsSum += len(nameWarnings)
+			}
+			routeWarnings := checkRoutesOfStop(mbs[i], routeIndex)
+			if len(routeWarnings) > 0 {
+				result[i].OsmReference = result[i].OsmReference + "<p>routes:<br />- " + strings.Join(routeWarnings, "<br />- ") + "</p>"
+				warningsSum += len(routeWarnings)
+			}
+		}
+		result[i].Warnings = warningsSum - warningsBefore
 	}
 
 	var templateData TemplateData
 	templateData.Rows = result
 	templateData.GenDate = time.Now()
-	templateData.IgnoredBusStops = fmt.Sprint(ignoreBusStopsWithOperators)
+	templateData.IgnoredBusStops = fmt.Sprint(ignoredOperators)
 	templateData.Title = "VVR-OSM Haltestellenabgleich"
+	templateData.OverpassSource = newOverpassData.Endpoint
+	templateData.LinesWithoutRoute = strings.Join(routeIndex.getLinesWithoutRoute(mbs), ", ")
 	templateData.Stats.VvrStops = vvrBusStopSum
 	templateData.Stats.OsmStops = totalOsmElements
 	templateData.Stats.OsmStopsNoName = osmStopsNoName
@@ -372,5 +367,15 @@ func main() {
 	templateData.Stats.OsmStopsMatchingVvr = totalOsmElements - remainingOsmElements
 	templateData.Stats.VvrStopsWithOsmObject = vvrBusStopSum - remainingVvrStops
 	templateData.Stats.WarningsSum = warningsSum
-	writeTemplateToHTML(templateData)
+	templateData.Stats.OrphanPlatforms = orphanPlatforms
+	templateData.Stats.OrphanStopPositions = orphanStopPositions
+	templateData.Stats.BusRoutes = len(busRoutes)
+	if config.AmenityAudit.Enabled {
+		templateData.AmenityAudit = buildAmenityAudit(auditedStops)
+	}
+	if config.Survey.Enabled {
+		templateData.Survey = buildSurveyList(auditedStops, templateData.GenDate)
+		templateData.SurveyMaxAgeInYears = config.Survey.MaxAgeInYears
+	}
+	return templateData
 }
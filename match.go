@@ -0,0 +1,79 @@
+package main
+
+import "log"
+
+// takeMatchingElements splits elements into the ones matching and the rest
+func takeMatchingElements(elements []OsmElement, isMatching func(OsmElement) bool) ([]OsmElement, []OsmElement) {
+	var taken, rest []OsmElement
+	for i := 0; i < len(elements); i++ {
+		if isMatching(elements[i]) {
+			taken = append(taken, elements[i])
+		} else {
+			rest = append(rest, elements[i])
+		}
+	}
+	return taken, rest
+}
+
+// matchVvrWithOsm assigns the OSM elements to the VVR bus stops. Elements whose
+// ref:IFOPT belongs to the DHID of a VVR stop are assigned first, the others are
+// matched by name. It returns the matched bus stops and the elements which could
+// not be assigned to any VVR stop.
+func matchVvrWithOsm(vvr VvrData, elements []OsmElement, cities []string) ([]MatchedBusStop, []OsmElement) {
+	var mbs []MatchedBusStop
+	for i := 0; i < len(vvr.CityResults); i++ {
+		for k := 0; k < len(vvr.CityResults[i].Result); k++ {
+			oneBusStop := vvr.CityResults[i].Result[k]
+			var oneMatch MatchedBusStop
+			oneMatch.Name = oneBusStop.Value
+			oneMatch.Linien = oneBusStop.Linien
+			oneMatch.Lines = parseVvrLinien(oneBusStop.Linien)
+			oneMatch.VvrID = oneBusStop.ID
+			// use VVR ID to remove duplicate VVR entities
+			vvrIsDuplicate := false
+			for p := 0; p < len(mbs); p++ {
+				if mbs[p].VvrID == oneMatch.VvrID {
+					vvrIsDuplicate = true
+					break
+				}
+			}
+			vvrIsSpecialDestination := false
+			for specDest := 0; specDest < len(ignoreVvrStops); specDest++ {
+				if oneMatch.Name == ignoreVvrStops[specDest] {
+					vvrIsSpecialDestination = true
+					break
+				}
+			}
+			if !vvrIsDuplicate && !vvrIsSpecialDestination {
+				oneMatch.City = parseVvrStopName(oneMatch.Name).City()
+				oneMatch.DHID = getDhidOfVvrStop(oneBusStop)
+				mbs = append(mbs, oneMatch)
+			}
+		}
+	}
+
+	remaining := elements
+	// prefer exact ref:IFOPT matches over name matching
+	for i := 0; i < len(mbs); i++ {
+		if mbs[i].DHID == "" {
+			continue
+		}
+		var taken []OsmElement
+		taken, remaining = takeMatchingElements(remaining, func(e OsmElement) bool {
+			return doesIfoptBelongToDhid(e.Tags.RefIFOPT(), mbs[i].DHID)
+		})
+		if *debug && len(taken) > 0 {
+			log.Printf("matched %d OSM elements via ref:IFOPT to %s\n", len(taken), mbs[i].Name)
+		}
+		mbs[i].Elements = append(mbs[i].Elements, taken...)
+	}
+	// one VVR element can match multiple OSM objects
+	for i := 0; i < len(mbs); i++ {
+		var taken []OsmElement
+		taken, remaining = takeMatchingElements(remaining, func(e OsmElement) bool {
+			return doesOsmElementMatchVvrElement(e, mbs[i].Name, cities)
+		})
+		mbs[i].Elements = append(mbs[i].Elements, taken...)
+	}
+	return mbs, remaining
+}
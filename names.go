@@ -0,0 +1,77 @@
+package main
+
+import (
+	"html"
+	"sort"
+	"strings"
+)
+
+// splitCityPrefix splits a name like "Stralsund, Hauptbahnhof" into the city and
+// the stop part if it starts with one of the given cities. Otherwise the city
+// is empty and the whole name is returned as stop part.
+func splitCityPrefix(name string, cities []string) (string, string) {
+	parts := strings.SplitN(name, ",", 2)
+	if len(parts) == 2 {
+		prefix := strings.TrimSpace(parts[0])
+		for _, city := range cities {
+			if strings.EqualFold(prefix, city) {
+				return prefix, strings.TrimSpace(parts[1])
+			}
+		}
+	}
+	return "", strings.TrimSpace(name)
+}
+
+// getCanonicalName returns the name proposed for all OSM objects of a VVR stop
+// according to the configured canonical name form
+func getCanonicalName(vvrName string) string {
+	if config.CanonicalNameForm == canonicalNameWithoutCity {
+		parts := strings.SplitN(vvrName, ",", 2)
+		if len(parts) == 2 {
+			return strings.TrimSpace(parts[1])
+		}
+	}
+	return strings.TrimSpace(vvrName)
+}
+
+// checkNameConsistency reports differing name tags of the OSM objects of a
+// matched VVR stop. Names which only differ in their city prefix are reported
+// separately from names which are spelled differently.
+func checkNameConsistency(stop MatchedBusStop, cities []string) []string {
+	var warnings []string
+	elementsByName := make(map[string][]OsmElement)
+	for _, e := range stop.Elements {
+		if e.Tags.Name() != "" {
+			elementsByName[e.Tags.Name()] = append(elementsByName[e.Tags.Name()], e)
+		}
+	}
+	if len(elementsByName) < 2 {
+		return warnings
+	}
+	var names []string
+	stopParts := make(map[string]bool)
+	for name := range elementsByName {
+		names = append(names, name)
+		_, stopPart := splitCityPrefix(name, cities)
+		stopParts[stopPart] = true
+	}
+	sort.Strings(names)
+	var details []string
+	for _, name := range names {
+		var links []string
+		for _, e := range elementsByName[name] {
+			links = append(links, getOsmLink(e))
+		}
+		form := "without city"
+		if city, _ := splitCityPrefix(name, cities); city != "" {
+			form = "with city " + city
+		}
+		details = append(details, strings.Join(links, ", ")+": <code>name="+html.EscapeString(name)+"</code> ("+html.EscapeString(form)+")")
+	}
+	problem := warning_names_spelled_differently
+	if len(stopParts) == 1 {
+		problem = warning_names_city_prefix_differs
+	}
+	warnings = append(warnings, problem+":<br />"+strings.Join(details, "<br />")+"<br />proposed: <code>name="+html.EscapeString(getCanonicalName(stop.Name))+"</code>")
+	return warnings
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// types of normalization rules
+const ruleTypeSubstring = "substring"
+const ruleTypeWord = "word"
+const ruleTypePrefix = "prefix"
+const ruleTypeRegex = "regex"
+
+// NormalizationRule replaces Search by Replace in lower case stop names. Type
+// tells how Search is applied: "substring" (default) replaces it anywhere,
+// "word" only as a whole token, "prefix" only at the start of the name and
+// "regex" treats Search as regular expression and Replace may use $1 etc.
+type NormalizationRule struct {
+	Search  string `json:"search"`
+	Replace string `json:"replace"`
+	Type    string `json:"type,omitempty"`
+}
+
+// NormalizationExample is a stop name and its expected result after applying all normalization rules
+type NormalizationExample struct {
+	Name       string `json:"name"`
+	Normalized string `json:"normalized"`
+}
+
+type compiledNormalizationRule struct {
+	rule    NormalizationRule
+	regex   *regexp.Regexp
+	replace string
+}
+
+// nonLetter matches a character which cannot be part of a word, including umlauts as letters
+const nonLetter = `[^\p{L}\p{N}]`
+
+func isLetterOrNumber(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsNumber(r)
+}
+
+func compileNormalizationRule(rule NormalizationRule) (compiledNormalizationRule, error) {
+	c := compiledNormalizationRule{rule: rule}
+	if rule.Search == "" {
+		return c, fmt.Errorf("normalization rule with empty search, replace is %q", rule.Replace)
+	}
+	literalReplace := strings.ReplaceAll(rule.Replace, "$", "$$")
+	var expr string
+	switch rule.Type {
+	case "", ruleTypeSubstring:
+		expr = regexp.QuoteMeta(rule.Search)
+		c.replace = literalReplace
+	case ruleTypeWord:
+		// a boundary is only needed where the search starts or ends with a letter,
+		// e.g. "gr." must not be preceded by a letter, but may be followed by one
+		prefix, suffix := "()", "()"
+		runes := []rune(rule.Search)
+		if isLetterOrNumber(runes[0]) {
+			prefix = `(^|` + nonLetter + `)`
+		}
+		if isLetterOrNumber(runes[len(runes)-1]) {
+			suffix = `($|` + nonLetter + `)`
+		}
+		expr = prefix + regexp.QuoteMeta(rule.Search) + suffix
+		c.replace = "${1}" + literalReplace + "${2}"
+	case ruleTypePrefix:
+		expr = `^` + regexp.QuoteMeta(rule.Search)
+		c.replace = literalReplace
+	case ruleTypeRegex:
+		expr = rule.Search
+		c.replace = rule.Replace
+	default:
+		return c, fmt.Errorf("normalization rule %q has unknown type %q", rule.Search, rule.Type)
+	}
+	regex, err := regexp.Compile(expr)
+	if err != nil {
+		return c, fmt.Errorf("normalization rule %q: %v", rule.Search, err)
+	}
+	c.regex = regex
+	return c, nil
+}
+
+func (c compiledNormalizationRule) apply(name string) string {
+	if c.rule.Type == ruleTypeWord {
+		// apply twice, because adjacent matches share the separating character
+		name = c.regex.ReplaceAllString(name, c.replace)
+	}
+	return c.regex.ReplaceAllString(name, c.replace)
+}
+
+// NormalizationPipeline applies the normalization rules in order
+type NormalizationPipeline struct {
+	rules []compiledNormalizationRule
+	cache sync.Map
+}
+
+func compileNormalizationPipeline(rules []NormalizationRule) (*NormalizationPipeline, error) {
+	p := NormalizationPipeline{}
+	for _, rule := range rules {
+		c, err := compileNormalizationRule(rule)
+		if err != nil {
+			return nil, err
+		}
+		p.rules = append(p.rules, c)
+	}
+	return &p, nil
+}
+
+// Normalize harmonizes a stop name by lower casing it and applying all rules
+func (p *NormalizationPipeline) Normalize(name string) string {
+	if normalized, exists := p.cache.Load(name); exists {
+		return normalized.(string)
+	}
+	normalized := strings.ToLower(name)
+	for _, c := range p.rules {
+		normalized = c.apply(normalized)
+	}
+	normalized = strings.TrimSpace(normalized)
+	p.cache.Store(name, normalized)
+	return normalized
+}
+
+// NormalizationStep is the result of a normalization rule which changed the name
+type NormalizationStep struct {
+	Rule   NormalizationRule
+	Result string
+}
+
+// NormalizeWithSteps normalizes name like Normalize and returns the result of
+// every rule which changed the name
+func (p *NormalizationPipeline) NormalizeWithSteps(name string) []NormalizationStep {
+	normalized := strings.ToLower(name)
+	steps := []NormalizationStep{{Rule: NormalizationRule{Type: "lower case"}, Result: normalized}}
+	for _, c := range p.rules {
+		result := c.apply(normalized)
+		if result != normalized {
+			steps = append(steps, NormalizationStep{Rule: c.rule, Result: result})
+		}
+		normalized = result
+	}
+	if trimmed := strings.TrimSpace(normalized); trimmed != normalized {
+		steps = append(steps, NormalizationStep{Rule: NormalizationRule{Type: "trim"}, Result: trimmed})
+	}
+	return steps
+}
+
+// checkExamples returns an error for the first example the pipeline does not normalize as expected
+func (p *NormalizationPipeline) checkExamples(examples []NormalizationExample) error {
+	for _, example := range examples {
+		if normalized := p.Normalize(example.Name); normalized != example.Normalized {
+			return fmt.Errorf("normalization example %q results in %q instead of %q", example.Name, normalized, example.Normalized)
+		}
+	}
+	return nil
+}
+
+var normalizationPipeline = mustCompileNormalizationPipeline(config.NormalizationRules)
+
+func mustCompileNormalizationPipeline(rules []NormalizationRule) *NormalizationPipeline {
+	p, err := compileNormalizationPipeline(rules)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// normalizeStopName harmonizes abbreviations, special chars etc. of a stop name
+func normalizeStopName(name string) string {
+	return normalizationPipeline.Normalize(name)
+}
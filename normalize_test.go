@@ -0,0 +1,101 @@
+package main
+
+import "testing"
+
+func TestNormalizeDefaultRules(t *testing.T) {
+	p, err := compileNormalizationPipeline(newDefaultConfig().NormalizationRules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name       string
+		normalized string
+	}{
+		{"Stralsund, Hauptbahnhof", "stralsund hauptbahnhof"},
+		{"Gr. Mohrdorf, Ort", "gross mohrdorf ort"},
+		{"Gr.Mohrdorf", "grossmohrdorf"},
+		{"Bergen, Krhs.", "bergen krankenhaus"},
+		{"Stralsund, L.-Feuchtwanger-Str.", "stralsund lion feuchtwanger str"},
+		{"Grimmen, Straße d. Jugend", "grimmen str der jugend"},
+		{"Kandelin (Süderholz)", "kandelin"},
+		{"Neu Lüdershg.", "neu luedershagen"},
+		{"Negast, Wpl.", "negast wendeplatz"},
+		{"Zingst, Hanshäger Str.", "zingst hanshaeger str"},
+		{"Stralsund, Fockerstraße", "stralsund foggerstr"},
+		{"Elmemhorst, Dorf", "elmenhorst dorf"},
+		{"Bergen, Haupthst.", "bergen haupthaltestelle"},
+		{"Bartelshg.II", "bartelshagen ii"},
+		{"Stralsund, Deutsche Rentenversicherung", "stralsund drv"},
+		{"Stralsund,  Carl-Heydemann-Ring", "stralsund carl heydemann ring"},
+	}
+	for _, test := range tests {
+		if normalized := p.Normalize(test.name); normalized != test.normalized {
+			t.Errorf("Normalize(%q) = %q, want %q", test.name, normalized, test.normalized)
+		}
+	}
+}
+
+func TestNormalizeRuleTypes(t *testing.T) {
+	tests := []struct {
+		rule       NormalizationRule
+		name       string
+		normalized string
+	}{
+		// substring replaces anywhere
+		{NormalizationRule{Search: "wpl", Replace: "wendeplatz"}, "Negast, Wpl.", "negast, wendeplatz."},
+		{NormalizationRule{Search: "gr.", Replace: "groß"}, "Hagr.", "hagroß"},
+		// word only replaces whole tokens
+		{NormalizationRule{Search: "gr.", Replace: "groß", Type: ruleTypeWord}, "Gr. Mohrdorf", "groß mohrdorf"},
+		{NormalizationRule{Search: "gr.", Replace: "groß", Type: ruleTypeWord}, "Gr.Mohrdorf", "großmohrdorf"},
+		{NormalizationRule{Search: "gr.", Replace: "groß", Type: ruleTypeWord}, "Agr. Genossenschaft", "agr. genossenschaft"},
+		{NormalizationRule{Search: "gr.", Replace: "groß", Type: ruleTypeWord}, "Langr. Kirche", "langr. kirche"},
+		{NormalizationRule{Search: "str", Replace: "straße", Type: ruleTypeWord}, "Bahnhofstr", "bahnhofstr"},
+		{NormalizationRule{Search: "str", Replace: "straße", Type: ruleTypeWord}, "Stralsund, Str der Jugend", "stralsund, straße der jugend"},
+		{NormalizationRule{Search: "ot", Replace: "ortsteil", Type: ruleTypeWord}, "Kloster, OT Vitte", "kloster, ortsteil vitte"},
+		// prefix only replaces at the start of the name
+		{NormalizationRule{Search: "hst ", Replace: "", Type: ruleTypePrefix}, "Hst Binz, Hst Mitte", "binz, hst mitte"},
+		{NormalizationRule{Search: "gr.", Replace: "groß", Type: ruleTypePrefix}, "Klein Gr. Kordshagen", "klein gr. kordshagen"},
+		// regex may use groups
+		{NormalizationRule{Search: `^(\pL+), (\pL+)$`, Replace: "$2 $1", Type: ruleTypeRegex}, "Sassnitz, Hafen", "hafen sassnitz"},
+		{NormalizationRule{Search: `\s+`, Replace: " ", Type: ruleTypeRegex}, "Binz,   Strandpromenade", "binz, strandpromenade"},
+		// a literal replace must not expand $
+		{NormalizationRule{Search: "ab", Replace: "$1", Type: ruleTypeWord}, "ab", "$1"},
+	}
+	for _, test := range tests {
+		p, err := compileNormalizationPipeline([]NormalizationRule{test.rule})
+		if err != nil {
+			t.Fatalf("rule %+v: %v", test.rule, err)
+		}
+		if normalized := p.Normalize(test.name); normalized != test.normalized {
+			t.Errorf("rule %+v: Normalize(%q) = %q, want %q", test.rule, test.name, normalized, test.normalized)
+		}
+	}
+}
+
+func TestCompileNormalizationRuleErrors(t *testing.T) {
+	tests := []NormalizationRule{
+		{Search: "", Replace: "x"},
+		{Search: "gr.", Replace: "groß", Type: "unknown"},
+		{Search: "(", Replace: "", Type: ruleTypeRegex},
+	}
+	for _, rule := range tests {
+		if _, err := compileNormalizationPipeline([]NormalizationRule{rule}); err == nil {
+			t.Errorf("rule %+v: expected an error", rule)
+		}
+	}
+}
+
+func TestCheckExamples(t *testing.T) {
+	p, err := compileNormalizationPipeline(newDefaultConfig().NormalizationRules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = p.checkExamples([]NormalizationExample{{Name: "Bergen, Krhs.", Normalized: "bergen krankenhaus"}})
+	if err != nil {
+		t.Error(err)
+	}
+	err = p.checkExamples([]NormalizationExample{{Name: "Bergen, Krhs.", Normalized: "bergen krhs"}})
+	if err == nil {
+		t.Error("expected an error for a wrong example")
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// areaIDOffset is added to a relation ID to get the ID of the Overpass area derived from it
+const areaIDOffset = 3600000000
+
+// OverpassArea is a search area given by an OSM relation, e.g. a county or municipality
+type OverpassArea struct {
+	Name     string `json:"name"`
+	Relation int64  `json:"relation"`
+}
+
+// OverpassFilter selects OSM objects of the given types having all of the tags
+type OverpassFilter struct {
+	// Types is the Overpass QL type selector, e.g. node, way, rel, nw or nwr
+	Types string `json:"types"`
+	// Tags are tag conditions like "key", "key=value", "key!=value" or "key~regex"
+	Tags []string `json:"tags"`
+}
+
+// OverpassQueryConfig describes where and what to search for in the Overpass API.
+// All given spatial restrictions (areas, bbox and polygon) apply at the same time.
+type OverpassQueryConfig struct {
+	TimeoutInSeconds int            `json:"timeout"`
+	Areas            []OverpassArea `json:"areas"`
+	// Bbox is south, west, north, east
+	Bbox []float64 `json:"bbox"`
+	// Polygon is a list of lat, lon pairs
+	Polygon [][2]float64     `json:"polygon"`
+	Filters []OverpassFilter `json:"filters"`
+	// Output is the Overpass output mode, "center" adds a center to ways and
+	// relations, "geom" adds their full geometry, empty means plain "out"
+	Output string `json:"output"`
+}
+
+func quoteOverpassString(s string) string {
+	s = strings.ReplaceAll(s, "\\", "\\\\")
+	s = strings.ReplaceAll(s, "\"", "\\\"")
+	return "\"" + s + "\""
+}
+
+// buildOverpassTagFilter converts a tag condition like "key=value" to Overpass QL
+func buildOverpassTagFilter(tag string) string {
+	for _, op := range []string{"!=", "=", "~"} {
+		if i := strings.Index(tag, op); i > 0 {
+			return "[" + quoteOverpassString(tag[:i]) + op + quoteOverpassString(tag[i+len(op):]) + "]"
+		}
+	}
+	return "[" + quoteOverpassString(tag) + "]"
+}
+
+func formatCoordinate(c float64) string {
+	return strconv.FormatFloat(c, 'f', -1, 64)
+}
+
+// buildOverpassQuery generates the Overpass QL query for the given config
+func buildOverpassQuery(q OverpassQueryConfig) (string, error) {
+	if len(q.Filters) == 0 {
+		return "", errors.New("overpass query: no filters given")
+	}
+	if len(q.Areas) == 0 && len(q.Bbox) == 0 && len(q.Polygon) == 0 {
+		return "", errors.New("overpass query: neither areas, bbox nor polygon given")
+	}
+	if len(q.Bbox) != 0 && len(q.Bbox) != 4 {
+		return "", fmt.Errorf("overpass query: bbox needs 4 values, got %d", len(q.Bbox))
+	}
+	if q.Output != "" && q.Output != "center" && q.Output != "geom" {
+		return "", fmt.Errorf("overpass query: unknown output mode %s", q.Output)
+	}
+	if len(q.Polygon) != 0 && len(q.Polygon) < 3 {
+		return "", fmt.Errorf("overpass query: polygon needs at least 3 points, got %d", len(q.Polygon))
+	}
+
+	var sb strings.Builder
+	sb.WriteString("[out:json][timeout:" + strconv.Itoa(q.TimeoutInSeconds) + "];")
+	spatialFilter := ""
+	if len(q.Areas) > 0 {
+		sb.WriteString("(")
+		for _, area := range q.Areas {
+			sb.WriteString("area(" + strconv.FormatInt(areaIDOffset+area.Relation, 10) + ");")
+		}
+		sb.WriteString(")->.searchArea;")
+		spatialFilter += "(area.searchArea)"
+	}
+	if len(q.Bbox) == 4 {
+		var coords []string
+		for _, c := range q.Bbox {
+			coords = append(coords, formatCoordinate(c))
+		}
+		spatialFilter += "(" + strings.Join(coords, ",") + ")"
+	}
+	if len(q.Polygon) > 0 {
+		var coords []string
+		for _, p := range q.Polygon {
+			coords = append(coords, formatCoordinate(p[0])+" "+formatCoordinate(p[1]))
+		}
+		spatialFilter += "(poly:" + quoteOverpassString(strings.Join(coords, " ")) + ")"
+	}
+	sb.WriteString("(")
+	for _, f := range q.Filters {
+		if f.Types == "" {
+			return "", errors.New("overpass query: filter without types")
+		}
+		sb.WriteString(f.Types)
+		for _, tag := range f.Tags {
+			sb.WriteString(buildOverpassTagFilter(tag))
+		}
+		sb.WriteString(spatialFilter + ";")
+	}
+	sb.WriteString(");out")
+	if q.Output != "" {
+		sb.WriteString(" " + q.Output)
+	}
+	sb.WriteString(";")
+	return sb.String(), nil
+}
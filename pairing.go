@@ -0,0 +1,47 @@
+package main
+
+import "fmt"
+
+// checkPlatformPairing pairs every platform of a matched stop with the nearest
+// stop position and returns warnings for platforms without a stop position and
+// pairs which are further apart than the configured distance
+func checkPlatformPairing(stop MatchedBusStop) []string {
+	var warnings []string
+	var platforms, stopPositions []OsmElement
+	for _, e := range stop.Elements {
+		switch e.Tags.PublicTransport() {
+		case "platform":
+			if e.Type != "relation" {
+				platforms = append(platforms, e)
+			}
+		case "stop_position":
+			stopPositions = append(stopPositions, e)
+		}
+	}
+	for _, p := range platforms {
+		if len(stopPositions) == 0 {
+			warnings = append(warnings, getOsmLink(p)+" "+warning_platform_without_stop_position)
+			continue
+		}
+		var nearest OsmElement
+		nearestDistance := -1.0
+		for _, sp := range stopPositions {
+			distance, ok := getDistanceBetweenElements(p, sp)
+			if !ok {
+				continue
+			}
+			if nearestDistance < 0 || distance < nearestDistance {
+				nearest = sp
+				nearestDistance = distance
+			}
+		}
+		if nearestDistance < 0 {
+			// without coordinates we cannot tell anything about the distance
+			continue
+		}
+		if nearestDistance > config.MaxPlatformStopDistanceInMeters {
+			warnings = append(warnings, fmt.Sprintf("%s %s: nearest stop_position %s is %.0f m away (max. %.0f m)", getOsmLink(p), warning_platform_stop_position_too_far, getOsmLink(nearest), nearestDistance, config.MaxPlatformStopDistanceInMeters))
+		}
+	}
+	return warnings
+}
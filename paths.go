@@ -0,0 +1,67 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+)
+
+// PathSetting is a path which can be set via flag, environment variable and config file
+type PathSetting struct {
+	Target      *string
+	FlagName    string
+	EnvName     string
+	ConfigValue string
+}
+
+func addCacheDirFlag(fs *flag.FlagSet) {
+	fs.StringVar(&cacheDir, "cache-dir", cacheDir, "directory of the cached VVR and OSM data (env VVR_CACHE_DIR)")
+}
+
+func addOutputDirFlag(fs *flag.FlagSet) {
+	fs.StringVar(&outputDir, "output-dir", outputDir, "directory of the match result and the report (env VVR_OUTPUT_DIR)")
+}
+
+func addLockFileFlag(fs *flag.FlagSet) {
+	fs.StringVar(&lockFile, "lock-file", lockFile, "lock file preventing concurrent runs (env VVR_LOCK_FILE)")
+}
+
+func addTemplateDirFlag(fs *flag.FlagSet) {
+	fs.StringVar(&templateDir, "template-dir", templateDir, "directory with templates overriding the embedded ones (env VVR_TEMPLATE_DIR)")
+}
+
+// resolveConfigPath makes a relative path of the config file relative to the
+// directory of the config file, so it does not depend on the working directory
+func resolveConfigPath(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(filepath.Dir(*configFile), path)
+}
+
+// applyPaths sets the paths which were not given as flag from the environment
+// or, if not set there either, from the config file
+func applyPaths(fs *flag.FlagSet) {
+	isFlagSet := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		isFlagSet[f.Name] = true
+	})
+	settings := []PathSetting{
+		{&cacheDir, "cache-dir", "VVR_CACHE_DIR", config.Paths.CacheDir},
+		{&outputDir, "output-dir", "VVR_OUTPUT_DIR", config.Paths.OutputDir},
+		{&lockFile, "lock-file", "VVR_LOCK_FILE", config.Paths.LockFile},
+		{&templateDir, "template-dir", "VVR_TEMPLATE_DIR", config.Paths.TemplateDir},
+	}
+	for _, setting := range settings {
+		if isFlagSet[setting.FlagName] {
+			continue
+		}
+		if value := os.Getenv(setting.EnvName); value != "" {
+			*setting.Target = value
+			continue
+		}
+		if setting.ConfigValue != "" {
+			*setting.Target = resolveConfigPath(setting.ConfigValue)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"html"
+	"sort"
+	"strings"
+)
+
+func isBusRoute(e OsmElement) bool {
+	return e.Type == "relation" && e.Tags.Type() == "route" && e.Tags.Get("route") == "bus"
+}
+
+// RouteIndex allows to look up bus route relations by their line and their members
+type RouteIndex struct {
+	ByLine   map[string][]OsmElement
+	ByMember map[string][]OsmElement
+}
+
+func buildRouteIndex(routes []OsmElement) RouteIndex {
+	index := RouteIndex{
+		ByLine:   make(map[string][]OsmElement),
+		ByMember: make(map[string][]OsmElement),
+	}
+	for _, r := range routes {
+		line := strings.TrimSpace(r.Tags.Ref())
+		if line != "" {
+			index.ByLine[line] = append(index.ByLine[line], r)
+		}
+		for _, m := range r.Members {
+			key := getOsmElementKey(m.Type, m.Ref)
+			index.ByMember[key] = append(index.ByMember[key], r)
+		}
+	}
+	return index
+}
+
+// getLinesWithoutRoute returns all lines served according to VVR which have no route relation in OSM
+func (index RouteIndex) getLinesWithoutRoute(mbs []MatchedBusStop) []string {
+	known := make(map[string]bool)
+	var lines []string
+	for _, stop := range mbs {
+		for _, line := range getLinesOfStop(stop) {
+			if known[line] {
+				continue
+			}
+			known[line] = true
+			if len(index.ByLine[line]) == 0 {
+				lines = append(lines, line)
+			}
+		}
+	}
+	sort.Slice(lines, func(a, b int) bool { return isLineNameLess(lines[a], lines[b]) })
+	return lines
+}
+
+// getLinesOfStop returns the lines serving the stop according to VVR
+func getLinesOfStop(stop MatchedBusStop) []string {
+	return getLineNames(stop.Lines)
+}
+
+// checkRoutesOfStop compares the lines serving a matched stop according to VVR
+// with the route relations its OSM elements are member of
+func checkRoutesOfStop(stop MatchedBusStop, index RouteIndex) []string {
+	var warnings []string
+	vvrLines := make(map[string]bool)
+	for _, line := range getLinesOfStop(stop) {
+		vvrLines[line] = true
+	}
+	osmRoutes := make(map[int64]OsmElement)
+	osmLines := make(map[string]bool)
+	for _, e := range stop.Elements {
+		for _, r := range index.ByMember[getOsmElementKey(e.Type, e.ID)] {
+			osmRoutes[r.ID] = r
+			osmLines[strings.TrimSpace(r.Tags.Ref())] = true
+		}
+	}
+	for _, line := range getLinesOfStop(stop) {
+		if len(index.ByLine[line]) == 0 || osmLines[line] {
+			// lines without any route relation are reported once for the whole network
+			continue
+		}
+		var links []string
+		for _, r := range index.ByLine[line] {
+			links = append(links, getOsmLink(r))
+		}
+		warnings = append(warnings, "line "+html.EscapeString(line)+" "+warning_route_member_missing+" "+strings.Join(links, ", "))
+	}
+	var routeIDs []int64
+	for id := range osmRoutes {
+		routeIDs = append(routeIDs, id)
+	}
+	sort.Slice(routeIDs, func(a, b int) bool { return routeIDs[a] < routeIDs[b] })
+	for _, id := range routeIDs {
+		r := osmRoutes[id]
+		if !vvrLines[strings.TrimSpace(r.Tags.Ref())] {
+			warnings = append(warnings, getOsmLink(r)+" of line "+html.EscapeString(r.Tags.Ref())+" "+warning_route_member_not_in_vvr)
+		}
+	}
+	return warnings
+}
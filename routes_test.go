@@ -0,0 +1,21 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetLinesWithoutRoute(t *testing.T) {
+	index := buildRouteIndex([]OsmElement{
+		{Type: "relation", ID: 1, Tags: OsmTags{"type": "route", "route": "bus", "ref": "3"}},
+	})
+	mbs := []MatchedBusStop{
+		{Name: "Stralsund, Hauptbahnhof", Lines: []VvrLine{{Name: "10"}, {Name: "2"}, {Name: "3"}}},
+		{Name: "Bergen, Markt", Lines: []VvrLine{{Name: "N1"}, {Name: "2"}, {Name: "21"}, {Name: "1"}}},
+	}
+	lines := index.getLinesWithoutRoute(mbs)
+	expected := []string{"1", "2", "10", "21", "N1"}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Errorf("getLinesWithoutRoute = %v, want %v", lines, expected)
+	}
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RefreshStatus holds the state of the refreshs triggered via the server
+type RefreshStatus struct {
+	IsRunning bool
+	Start     time.Time
+	Duration  time.Duration
+	Error     string
+}
+
+// CacheFileStatus describes the age of a cached or generated file
+type CacheFileStatus struct {
+	Path    string
+	Exists  bool
+	ModTime time.Time
+	Age     time.Duration
+}
+
+// SnapshotLink holds the file names of a snapshot and its report
+type SnapshotLink struct {
+	Name   string
+	Report string
+}
+
+// StatusData is rendered by the status template
+type StatusData struct {
+	Title           string
+	Now             time.Time
+	IsOffline       bool
+	LockHolder      *LockInfo
+	Files           []CacheFileStatus
+	OsmBase         time.Time
+	Result          TemplateData
+	Refresh         RefreshStatus
+	Snapshots       []SnapshotLink
+	SnapshotsError  string
+	RefreshDisabled bool
+}
+
+// refreshStatus is guarded by refreshMutex
+var refreshStatus RefreshStatus
+var refreshMutex sync.Mutex
+
+func getCacheFileStatus(path string, now time.Time) CacheFileStatus {
+	status := CacheFileStatus{Path: path}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return status
+	}
+	status.Exists = true
+	status.ModTime = fi.ModTime()
+	status.Age = now.Sub(fi.ModTime()).Round(time.Second)
+	return status
+}
+
+// handleRoot serves the generated files of the output directory, the report is the index
+func handleRoot(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/" {
+		http.Redirect(w, r, "/"+templateName+".html", http.StatusFound)
+		return
+	}
+	http.FileServer(http.Dir(outputDir)).ServeHTTP(w, r)
+}
+
+func handleMap(w http.ResponseWriter, r *http.Request) {
+	b, err := readTemplateFile(mapFile)
+	if err != nil {
+		log.Println("handleMap:", err)
+		http.Error(w, "map missing", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(b)
+}
+
+// handleHistory renders the report of a snapshot if its name ends with .html,
+// otherwise the snapshots are served as they are
+func handleHistory(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/"+historyDir+"/")
+	if !strings.HasSuffix(name, ".html") {
+		http.FileServer(http.Dir(outputDir)).ServeHTTP(w, r)
+		return
+	}
+	templateData, err := readResultSnapshot(strings.TrimSuffix(name, ".html") + ".json")
+	if err != nil {
+		if *verbose {
+			log.Println("handleHistory:", err)
+		}
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	err = executeReport(w, templateData)
+	if err != nil {
+		log.Println("handleHistory:", err)
+	}
+}
+
+func handleStatus(w http.ResponseWriter, r *http.Request) {
+	var data StatusData
+	data.Title = "Status VVR-OSM Haltestellenabgleich"
+	data.Now = time.Now()
+	data.IsOffline = *offline
+	data.LockHolder = getLockHolder(lockFile)
+	dataDir := getDataDir()
+	data.Files = []CacheFileStatus{
+		getCacheFileStatus(dataDir+string(os.PathSeparator)+vvrDataFile, data.Now),
+		getCacheFileStatus(dataDir+string(os.PathSeparator)+overpassDataFile, data.Now),
+		getCacheFileStatus(outputDir+string(os.PathSeparator)+resultDataFile, data.Now),
+		getCacheFileStatus(outputDir+string(os.PathSeparator)+templateName+".html", data.Now),
+	}
+	var overpass OverpassData
+	err := readCurrentJSON(&overpass)
+	if err != nil {
+		log.Println("handleStatus: error reading overpass data", err)
+	}
+	data.OsmBase = overpass.Osm3S.TimestampOsmBase
+	err = readCurrentJSON(&data.Result)
+	if err != nil {
+		log.Println("handleStatus: error reading match result", err)
+	}
+	data.Result.Rows = nil
+	refreshMutex.Lock()
+	data.Refresh = refreshStatus
+	refreshMutex.Unlock()
+	data.RefreshDisabled = data.Refresh.IsRunning || data.LockHolder != nil
+	snapshots, err := listResultSnapshots()
+	if err != nil {
+		data.SnapshotsError = err.Error()
+	}
+	for _, name := range snapshots {
+		data.Snapshots = append(data.Snapshots, SnapshotLink{Name: name, Report: strings.TrimSuffix(name, ".json") + ".html"})
+	}
+
+	htmlSource, err := parseTemplate(statusTemplateName)
+	if err != nil {
+		log.Println("handleStatus:", err)
+		http.Error(w, "status template missing", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	err = htmlSource.Execute(w, data)
+	if err != nil {
+		log.Println("handleStatus:", err)
+	}
+}
+
+// isSameOrigin reports whether a request was sent by a page of this server,
+// so other sites cannot trigger a refresh from the browser of a visitor.
+// Browsers send Origin with every POST, clients like curl without it are accepted.
+func isSameOrigin(r *http.Request) bool {
+	if site := r.Header.Get("Sec-Fetch-Site"); site != "" && site != "same-origin" && site != "none" {
+		return false
+	}
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Host == r.Host
+}
+
+// handleRefresh starts an update of the report in the background, unless
+// another run holds the lock file
+func handleRefresh(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "use POST to start a refresh", http.StatusMethodNotAllowed)
+		return
+	}
+	if !isSameOrigin(r) {
+		log.Println("handleRefresh: rejecting cross-origin request from", r.Header.Get("Origin"))
+		http.Error(w, "cross-origin requests are not allowed", http.StatusForbidden)
+		return
+	}
+	err := createLockFile(lockFile)
+	if err != nil {
+		http.Error(w, "a run is in progress already: "+err.Error(), http.StatusConflict)
+		return
+	}
+	refreshMutex.Lock()
+	refreshStatus.IsRunning = true
+	refreshStatus.Start = time.Now()
+	refreshMutex.Unlock()
+	go func() {
+		defer removeLockFile(lockFile)
+		err := updateReport()
+		refreshMutex.Lock()
+		defer refreshMutex.Unlock()
+		refreshStatus.IsRunning = false
+		refreshStatus.Duration = time.Since(refreshStatus.Start).Round(time.Millisecond)
+		refreshStatus.Error = ""
+		if err != nil {
+			log.Println("handleRefresh: refresh failed:", err)
+			refreshStatus.Error = err.Error()
+		}
+	}()
+	http.Redirect(w, r, "/status", http.StatusSeeOther)
+}
+
+// serve serves the report, the map, the exports and the snapshots on addr
+func serve(addr string) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", handleRoot)
+	mux.HandleFunc("/map", handleMap)
+	mux.HandleFunc("/"+historyDir+"/", handleHistory)
+	mux.HandleFunc("/status", handleStatus)
+	mux.HandleFunc("/refresh", handleRefresh)
+	log.Printf("serving on http://%s/\n", addr)
+	return http.ListenAndServe(addr, mux)
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIsSameOrigin(t *testing.T) {
+	tests := []struct {
+		header     string
+		value      string
+		sameOrigin bool
+	}{
+		{"", "", true},
+		{"Origin", "http://localhost:8080", true},
+		{"Origin", "http://evil.example", false},
+		{"Origin", "null", false},
+		{"Sec-Fetch-Site", "same-origin", true},
+		{"Sec-Fetch-Site", "none", true},
+		{"Sec-Fetch-Site", "cross-site", false},
+		{"Sec-Fetch-Site", "same-site", false},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest("POST", "http://localhost:8080/refresh", nil)
+		if test.header != "" {
+			r.Header.Set(test.header, test.value)
+		}
+		if sameOrigin := isSameOrigin(r); sameOrigin != test.sameOrigin {
+			t.Errorf("isSameOrigin with %s: %q = %v, want %v", test.header, test.value, sameOrigin, test.sameOrigin)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// snapshotTimeFormat is used in the file names of the snapshots, it sorts chronologically
+const snapshotTimeFormat = "20060102-150405"
+
+func getSnapshotDir() string {
+	return outputDir + string(os.PathSeparator) + historyDir
+}
+
+// writeResultSnapshot keeps a copy of the match result in the history
+func writeResultSnapshot(templateData TemplateData) error {
+	err := os.MkdirAll(getSnapshotDir(), os.ModePerm)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(templateData)
+	if err != nil {
+		return err
+	}
+	name, err := getNewSnapshotName(templateData.GenDate)
+	if err != nil {
+		return err
+	}
+	if *verbose {
+		log.Println("writeResultSnapshot: writing", name)
+	}
+	err = writeFileAtomically(getSnapshotDir()+string(os.PathSeparator)+name, b)
+	if err != nil {
+		return err
+	}
+	return pruneResultSnapshots(config.KeepSnapshots)
+}
+
+// getNewSnapshotName returns a file name for a snapshot of the given time which
+// is not used yet. A counter is appended if there is a snapshot of the same second.
+func getNewSnapshotName(t time.Time) (string, error) {
+	base := "result-" + t.Format(snapshotTimeFormat)
+	name := base + ".json"
+	for i := 1; ; i++ {
+		_, err := os.Stat(getSnapshotDir() + string(os.PathSeparator) + name)
+		if errors.Is(err, os.ErrNotExist) {
+			return name, nil
+		}
+		if err != nil {
+			return "", err
+		}
+		name = fmt.Sprintf("%s-%02d.json", base, i)
+	}
+}
+
+// pruneResultSnapshots deletes all but the newest keep snapshots, 0 keeps all
+func pruneResultSnapshots(keep int) error {
+	if keep <= 0 {
+		return nil
+	}
+	names, err := listResultSnapshots()
+	if err != nil {
+		return err
+	}
+	for i := keep; i < len(names); i++ {
+		if *verbose {
+			log.Println("pruneResultSnapshots: deleting", names[i])
+		}
+		err = os.Remove(getSnapshotDir() + string(os.PathSeparator) + names[i])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// listResultSnapshots returns the file names of the snapshots, newest first
+func listResultSnapshots() ([]string, error) {
+	entries, err := os.ReadDir(getSnapshotDir())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasPrefix(entry.Name(), "result-") && strings.HasSuffix(entry.Name(), ".json") {
+			names = append(names, entry.Name())
+		}
+	}
+	// compare without the file ending, so a counter sorts after the name without one
+	sort.Slice(names, func(i, j int) bool {
+		return strings.TrimSuffix(names[i], ".json") > strings.TrimSuffix(names[j], ".json")
+	})
+	return names, nil
+}
+
+// readResultSnapshot returns the match result of the snapshot with the given file name
+func readResultSnapshot(name string) (TemplateData, error) {
+	var templateData TemplateData
+	if name != filepath.Base(name) || !strings.HasPrefix(name, "result-") {
+		return templateData, errors.New("invalid snapshot name " + name)
+	}
+	b, err := os.ReadFile(getSnapshotDir() + string(os.PathSeparator) + name)
+	if err != nil {
+		return templateData, err
+	}
+	err = json.Unmarshal(b, &templateData)
+	return templateData, err
+}
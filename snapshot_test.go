@@ -0,0 +1,47 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWriteResultSnapshot(t *testing.T) {
+	oldOutputDir, oldKeepSnapshots := outputDir, config.KeepSnapshots
+	defer func() {
+		outputDir, config.KeepSnapshots = oldOutputDir, oldKeepSnapshots
+	}()
+	outputDir = t.TempDir()
+	config.KeepSnapshots = 3
+
+	first := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	second := first.Add(time.Second)
+	for _, genDate := range []time.Time{first, first, first, second} {
+		err := writeResultSnapshot(TemplateData{GenDate: genDate})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	names, err := listResultSnapshots()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the oldest one of the same second has been pruned
+	want := []string{
+		"result-20261018-120001.json",
+		"result-20261018-120000-02.json",
+		"result-20261018-120000-01.json",
+	}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("listResultSnapshots() = %v, want %v", names, want)
+	}
+
+	err = pruneResultSnapshots(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names, _ = listResultSnapshots()
+	if len(names) != 3 {
+		t.Errorf("pruneResultSnapshots(0) left %d snapshots, want 3", len(names))
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"html"
+	"strconv"
+	"strings"
+)
+
+// getOsmElementKey returns a unique key like node/123 for an OSM object
+func getOsmElementKey(osmType string, id int64) string {
+	return osmType + "/" + strconv.FormatInt(id, 10)
+}
+
+func isStopAreaRelation(e OsmElement) bool {
+	return e.Type == "relation" && e.Tags.PublicTransport() == "stop_area"
+}
+
+func isPlatformOrStopPosition(e OsmElement) bool {
+	return e.Type != "relation" && (e.Tags.PublicTransport() == "platform" || e.Tags.PublicTransport() == "stop_position")
+}
+
+// StopAreaIndex allows to look up OSM elements and the stop_area relations they are member of
+type StopAreaIndex struct {
+	Elements  map[string]OsmElement
+	StopAreas map[string][]OsmElement
+}
+
+func buildStopAreaIndex(elements []OsmElement) StopAreaIndex {
+	index := StopAreaIndex{
+		Elements:  make(map[string]OsmElement),
+		StopAreas: make(map[string][]OsmElement),
+	}
+	for _, e := range elements {
+		index.Elements[getOsmElementKey(e.Type, e.ID)] = e
+		if !isStopAreaRelation(e) {
+			continue
+		}
+		for _, m := range e.Members {
+			key := getOsmElementKey(m.Type, m.Ref)
+			index.StopAreas[key] = append(index.StopAreas[key], e)
+		}
+	}
+	return index
+}
+
+// isOrphan reports whether a platform or stop position is not member of any stop_area relation
+func (index StopAreaIndex) isOrphan(e OsmElement) bool {
+	return isPlatformOrStopPosition(e) && len(index.StopAreas[getOsmElementKey(e.Type, e.ID)]) == 0
+}
+
+// getStopAreasOfStop returns all stop_area relations which are part of the
+// matched stop or contain one of its platforms or stop positions
+func (index StopAreaIndex) getStopAreasOfStop(stop MatchedBusStop) []OsmElement {
+	var stopAreas []OsmElement
+	known := make(map[int64]bool)
+	add := func(r OsmElement) {
+		if !known[r.ID] {
+			known[r.ID] = true
+			stopAreas = append(stopAreas, r)
+		}
+	}
+	for _, e := range stop.Elements {
+		if isStopAreaRelation(e) {
+			add(e)
+		}
+		if isPlatformOrStopPosition(e) {
+			for _, r := range index.StopAreas[getOsmElementKey(e.Type, e.ID)] {
+				add(r)
+			}
+		}
+	}
+	return stopAreas
+}
+
+// checkStopAreaOfStop validates the stop_area relations of a matched VVR stop
+// and returns the warnings found
+func checkStopAreaOfStop(stop MatchedBusStop, index StopAreaIndex) []string {
+	var warnings []string
+	hasPlatformOrStopPosition := false
+	for _, e := range stop.Elements {
+		if isPlatformOrStopPosition(e) {
+			hasPlatformOrStopPosition = true
+			break
+		}
+	}
+	stopAreas := index.getStopAreasOfStop(stop)
+	if len(stopAreas) == 0 {
+		if hasPlatformOrStopPosition {
+			warnings = append(warnings, warning_stop_area_missing)
+		}
+		return warnings
+	}
+	if len(stopAreas) > 1 {
+		var links []string
+		for _, r := range stopAreas {
+			links = append(links, getOsmLink(r))
+		}
+		warnings = append(warnings, warning_stop_area_multiple+": "+strings.Join(links, ", "))
+	}
+	for _, r := range stopAreas {
+		hasPlatform := false
+		hasStopPosition := false
+		for _, m := range r.Members {
+			member, exists := index.Elements[getOsmElementKey(m.Type, m.Ref)]
+			if !exists {
+				continue
+			}
+			switch member.Tags.PublicTransport() {
+			case "platform":
+				hasPlatform = true
+			case "stop_position":
+				hasStopPosition = true
+			}
+			if member.Tags.Name() != "" && r.Tags.Name() != "" && member.Tags.Name() != r.Tags.Name() {
+				warnings = append(warnings, warning_stop_area_member_name+": "+getOsmLink(member)+" has <code>name="+html.EscapeString(member.Tags.Name())+"</code>, "+getOsmLink(r)+" has <code>name="+html.EscapeString(r.Tags.Name())+"</code>")
+			}
+		}
+		if !hasPlatform {
+			warnings = append(warnings, getOsmLink(r)+" "+warning_stop_area_without_platform)
+		}
+		if !hasStopPosition {
+			warnings = append(warnings, getOsmLink(r)+" "+warning_stop_area_without_stop_position)
+		}
+	}
+	return warnings
+}
+
+// getOsmLink returns a HTML link to the OSM object
+func getOsmLink(e OsmElement) string {
+	id := strconv.FormatInt(e.ID, 10)
+	return "<a href=\"http://osm.org/" + e.Type + "/" + id + "\">" + e.Type + " " + id + "</a>"
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"html"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SurveyStop is a VVR stop whose attributes need to be verified on site
+type SurveyStop struct {
+	Name      string
+	NrLines   int
+	Lines     string
+	LastCheck string
+	Reasons   string
+	lastCheck time.Time
+}
+
+// SurveyTown holds the stops to be surveyed in one town, most important first
+type SurveyTown struct {
+	Town  string
+	Stops []SurveyStop
+}
+
+// parseCheckDate parses the value of a check_date tag, which can be a day, a month or a year
+func parseCheckDate(value string) (time.Time, bool) {
+	for _, layout := range []string{"2006-01-02", "2006-01", "2006"} {
+		t, err := time.Parse(layout, strings.TrimSpace(value))
+		if err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// getSurveyReasons returns why the platform needs a survey, the time of the
+// oldest relevant check and whether it was checked at all
+func getSurveyReasons(e OsmElement, deadline time.Time) ([]string, time.Time, bool) {
+	var reasons []string
+	checkDate, isChecked := parseCheckDate(e.Tags.CheckDate())
+	switch {
+	case e.Tags.CheckDate() == "":
+		reasons = append(reasons, getOsmLink(e)+" has no check_date")
+	case !isChecked:
+		reasons = append(reasons, getOsmLink(e)+" has an invalid check_date="+html.EscapeString(e.Tags.CheckDate()))
+	case checkDate.Before(deadline):
+		reasons = append(reasons, getOsmLink(e)+" check_date="+html.EscapeString(e.Tags.CheckDate()))
+	}
+	if e.Tags.Shelter() == "yes" && e.Tags.CheckDateShelter() != "" {
+		shelterDate, ok := parseCheckDate(e.Tags.CheckDateShelter())
+		if ok && shelterDate.Before(deadline) {
+			reasons = append(reasons, getOsmLink(e)+" check_date:shelter="+html.EscapeString(e.Tags.CheckDateShelter()))
+			// without a valid check_date the platform counts as never checked
+			if isChecked && shelterDate.Before(checkDate) {
+				checkDate = shelterDate
+			}
+		}
+	}
+	return reasons, checkDate, isChecked
+}
+
+// buildSurveyList returns the stops whose platforms have not been verified
+// within the configured number of years, grouped by town and sorted by the
+// number of lines serving them
+func buildSurveyList(stops []MatchedBusStop, now time.Time) []SurveyTown {
+	deadline := now.AddDate(-config.Survey.MaxAgeInYears, 0, 0)
+	towns := make(map[string][]SurveyStop)
+	for _, stop := range stops {
+		var surveyStop SurveyStop
+		var reasons []string
+		isNeverChecked := false
+		for _, e := range stop.Elements {
+			if e.Tags.PublicTransport() != "platform" {
+				continue
+			}
+			elementReasons, checkDate, isChecked := getSurveyReasons(e, deadline)
+			if len(elementReasons) == 0 {
+				continue
+			}
+			reasons = append(reasons, elementReasons...)
+			if !isChecked {
+				isNeverChecked = true
+			} else if surveyStop.lastCheck.IsZero() || checkDate.Before(surveyStop.lastCheck) {
+				surveyStop.lastCheck = checkDate
+			}
+		}
+		if len(reasons) == 0 {
+			continue
+		}
+		surveyStop.Name = stop.Name
+		surveyStop.NrLines = len(stop.Lines)
+		surveyStop.Lines = strings.Join(getLineNames(stop.Lines), ", ")
+		surveyStop.Reasons = strings.Join(reasons, "<br />")
+		if isNeverChecked {
+			surveyStop.lastCheck = time.Time{}
+			surveyStop.LastCheck = "nie"
+		} else {
+			surveyStop.LastCheck = surveyStop.lastCheck.Format("2006-01-02")
+		}
+		town := stop.City
+		towns[town] = append(towns[town], surveyStop)
+	}
+
+	var result []SurveyTown
+	for town, surveyStops := range towns {
+		sort.SliceStable(surveyStops, func(a, b int) bool {
+			if surveyStops[a].NrLines != surveyStops[b].NrLines {
+				return surveyStops[a].NrLines > surveyStops[b].NrLines
+			}
+			if !surveyStops[a].lastCheck.Equal(surveyStops[b].lastCheck) {
+				return surveyStops[a].lastCheck.Before(surveyStops[b].lastCheck)
+			}
+			return surveyStops[a].Name < surveyStops[b].Name
+		})
+		result = append(result, SurveyTown{Town: town, Stops: surveyStops})
+	}
+	sort.Slice(result, func(a, b int) bool {
+		return result[a].Town < result[b].Town
+	})
+	return result
+}
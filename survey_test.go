@@ -0,0 +1,39 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetSurveyReasons(t *testing.T) {
+	deadline := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		tags      OsmTags
+		nrReasons int
+		checkDate string
+		isChecked bool
+	}{
+		{OsmTags{"check_date": "2024-05-01"}, 0, "2024-05-01", true},
+		{OsmTags{"check_date": "2020-05"}, 1, "2020-05-01", true},
+		{OsmTags{}, 1, "", false},
+		{OsmTags{"check_date": "soon"}, 1, "", false},
+		// an old shelter check is a reason, but does not count as check of the platform
+		{OsmTags{"shelter": "yes", "check_date:shelter": "2019"}, 2, "", false},
+		{OsmTags{"check_date": "soon", "shelter": "yes", "check_date:shelter": "2019"}, 2, "", false},
+		{OsmTags{"check_date": "2024-05-01", "shelter": "yes", "check_date:shelter": "2019"}, 1, "2019-01-01", true},
+		{OsmTags{"check_date": "2021-05-01", "shelter": "yes", "check_date:shelter": "2019"}, 2, "2019-01-01", true},
+	}
+	for _, test := range tests {
+		e := OsmElement{Type: "node", ID: 1, Tags: test.tags}
+		reasons, checkDate, isChecked := getSurveyReasons(e, deadline)
+		if len(reasons) != test.nrReasons {
+			t.Errorf("getSurveyReasons(%v) returned %d reasons, want %d: %v", test.tags, len(reasons), test.nrReasons, reasons)
+		}
+		if isChecked != test.isChecked {
+			t.Errorf("getSurveyReasons(%v) isChecked = %v, want %v", test.tags, isChecked, test.isChecked)
+		}
+		if test.isChecked && checkDate.Format("2006-01-02") != test.checkDate {
+			t.Errorf("getSurveyReasons(%v) checkDate = %s, want %s", test.tags, checkDate.Format("2006-01-02"), test.checkDate)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+// OsmTags holds all tags of an OSM element
+type OsmTags map[string]string
+
+// Get returns the value of key or an empty string if the tag is not set
+func (t OsmTags) Get(key string) string {
+	return t[key]
+}
+
+// Has reports whether the tag key is set
+func (t OsmTags) Has(key string) bool {
+	_, exists := t[key]
+	return exists
+}
+
+// accessors for the tags used by the checks, all other tags are available via Get
+
+func (t OsmTags) Bench() string            { return t["bench"] }
+func (t OsmTags) Bin() string              { return t["bin"] }
+func (t OsmTags) Bus() string              { return t["bus"] }
+func (t OsmTags) BusBay() string           { return t["bus_bay"] }
+func (t OsmTags) CheckDate() string        { return t["check_date"] }
+func (t OsmTags) CheckDateShelter() string { return t["check_date:shelter"] }
+func (t OsmTags) DeparturesBoard() string  { return t["departures_board"] }
+func (t OsmTags) Highway() string          { return t["highway"] }
+func (t OsmTags) Lit() string              { return t["lit"] }
+func (t OsmTags) LocalRef() string         { return t["local_ref"] }
+func (t OsmTags) Name() string             { return t["name"] }
+func (t OsmTags) NameDe() string           { return t["name:de"] }
+func (t OsmTags) Network() string          { return t["network"] }
+func (t OsmTags) NetworkGuid() string      { return t["network:guid"] }
+func (t OsmTags) NetworkShort() string     { return t["network:short"] }
+func (t OsmTags) Operator() string         { return t["operator"] }
+func (t OsmTags) PublicTransport() string  { return t["public_transport"] }
+func (t OsmTags) Ref() string              { return t["ref"] }
+func (t OsmTags) RefIFOPT() string         { return t["ref:IFOPT"] }
+func (t OsmTags) RouteRef() string         { return t["route_ref"] }
+func (t OsmTags) Shelter() string          { return t["shelter"] }
+func (t OsmTags) TactilePaving() string    { return t["tactile_paving"] }
+func (t OsmTags) Type() string             { return t["type"] }
+func (t OsmTags) Wheelchair() string       { return t["wheelchair"] }
@@ -24,30 +24,44 @@ type VvrData struct {
 	CityResults []VvrCity
 }
 
+// OsmCoordinate is a point given by latitude and longitude
+type OsmCoordinate struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+// OsmBounds is the bounding box of a way or relation
+type OsmBounds struct {
+	MinLat float64 `json:"minlat"`
+	MinLon float64 `json:"minlon"`
+	MaxLat float64 `json:"maxlat"`
+	MaxLon float64 `json:"maxlon"`
+}
+
+// OsmMember is a member of a relation
+type OsmMember struct {
+	Type     string          `json:"type"`
+	Ref      int64           `json:"ref"`
+	Role     string          `json:"role"`
+	Lat      float64         `json:"lat,omitempty"`
+	Lon      float64         `json:"lon,omitempty"`
+	Geometry []OsmCoordinate `json:"geometry,omitempty"`
+}
+
+// OsmElement is a node, way or relation as returned by the Overpass API. Lat and
+// Lon are only set for nodes, ways and relations have Center, Bounds or
+// Geometry depending on the requested output mode.
 type OsmElement struct {
-	Type string  `json:"type"`
-	ID   int64   `json:"id"`
-	Lat  float64 `json:"lat"`
-	Lon  float64 `json:"lon"`
-	Tags struct {
-		Bench            string `json:"bench"`
-		Bin              string `json:"bin"`
-		Bus              string `json:"bus"`
-		CheckDateShelter string `json:"check_date:shelter"`
-		DeparturesBoard  string `json:"departures_board"`
-		Highway          string `json:"highway"`
-		Lit              string `json:"lit"`
-		Name             string `json:"name"`
-		Network          string `json:"network"`
-		NetworkGuid      string `json:"network:guid"`
-		NetworkShort     string `json:"network:short"`
-		Operator         string `json:"operator"`
-		PublicTransport  string `json:"public_transport"`
-		RouteRef         string `json:"route_ref"`
-		Shelter          string `json:"shelter"`
-		TactilePaving    string `json:"tactile_paving"`
-		Wheelchair       string `json:"wheelchair"`
-	} `json:"tags,omitempty"`
+	Type     string          `json:"type"`
+	ID       int64           `json:"id"`
+	Lat      float64         `json:"lat"`
+	Lon      float64         `json:"lon"`
+	Center   *OsmCoordinate  `json:"center,omitempty"`
+	Bounds   *OsmBounds      `json:"bounds,omitempty"`
+	Geometry []OsmCoordinate `json:"geometry,omitempty"`
+	Nodes    []int64         `json:"nodes,omitempty"`
+	Members  []OsmMember     `json:"members,omitempty"`
+	Tags     OsmTags         `json:"tags,omitempty"`
 }
 
 // OverpassData holds the OSM data queried via overpass api
@@ -60,13 +74,17 @@ type OverpassData struct {
 		Copyright          string    `json:"copyright"`
 	} `json:"osm3s"`
 	Elements []OsmElement `json:"elements"`
+	// Endpoint is the Overpass API endpoint which served the data
+	Endpoint string `json:"endpoint,omitempty"`
 }
 
 // MatchedBusStops is a result of the merge of VVR data with OSM data
 type MatchedBusStop struct {
 	Name     string
 	VvrID    string
+	DHID     string
 	Linien   string
+	Lines    []VvrLine
 	City     string
 	Elements []OsmElement
 }
@@ -74,7 +92,10 @@ type MatchedBusStop struct {
 type MatchResult struct {
 	ID              int
 	VvrID           string
+	DHID            string
 	Name            string
+	City            string
+	Lines           string
 	IsIgnored       bool
 	IsInOSM         bool
 	IsInVVR         bool
@@ -82,6 +103,11 @@ type MatchResult struct {
 	NrPlatforms     int
 	NrStopPositions int
 	OsmReference    string
+	// Warnings is the number of warnings of this row
+	Warnings int
+	// Lat and Lon are the position of the first OSM object with coordinates
+	Lat float64
+	Lon float64
 }
 
 type Statistics struct {
@@ -93,6 +119,9 @@ type Statistics struct {
 	OsmStopsMatchingVvr   int
 	VvrStopsWithOsmObject int
 	WarningsSum           int
+	OrphanPlatforms       int
+	OrphanStopPositions   int
+	BusRoutes             int
 }
 
 type TemplateData struct {
@@ -100,5 +129,15 @@ type TemplateData struct {
 	GenDate         time.Time
 	IgnoredBusStops string
 	Title           string
-	Stats           Statistics
+	OverpassSource  string
+	// LinesWithoutRoute lists the VVR lines without a route relation in OSM
+	LinesWithoutRoute string
+	Stats             Statistics
+	// AmenityAudit is nil if the audit is disabled
+	AmenityAudit *AmenityAudit
+	// Survey is empty if the survey list is disabled
+	Survey              []SurveyTown
+	SurveyMaxAgeInYears int
+	// RunDuration is the time needed to read or fetch and match the data
+	RunDuration time.Duration
 }
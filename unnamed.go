@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// NameSuggestion is a VVR stop proposed as name for an unnamed OSM object
+type NameSuggestion struct {
+	Stop        MatchedBusStop
+	Distance    float64
+	SharedLines []string
+}
+
+// getLinesOfElement returns the lines of an OSM object from its route_ref and the route relations it is member of
+func getLinesOfElement(e OsmElement, routeIndex RouteIndex) []string {
+	routeRef := e.Tags.RouteRef()
+	for _, r := range routeIndex.ByMember[getOsmElementKey(e.Type, e.ID)] {
+		routeRef += ";" + r.Tags.Ref()
+	}
+	return parseRouteRef(routeRef)
+}
+
+// suggestNameForUnnamed returns the most likely VVR stop for an unnamed OSM
+// object. Candidates are the matched VVR stops within the configured distance,
+// stops sharing more lines with the object are preferred over nearer ones.
+func suggestNameForUnnamed(e OsmElement, mbs []MatchedBusStop, routeIndex RouteIndex) *NameSuggestion {
+	if _, _, ok := e.Coordinates(); !ok {
+		return nil
+	}
+	elementLines := getLinesOfElement(e, routeIndex)
+	var candidates []NameSuggestion
+	for _, stop := range mbs {
+		if stop.VvrID == "" {
+			continue
+		}
+		nearestDistance := -1.0
+		for _, stopElement := range stop.Elements {
+			distance, ok := getDistanceBetweenElements(e, stopElement)
+			if ok && (nearestDistance < 0 || distance < nearestDistance) {
+				nearestDistance = distance
+			}
+		}
+		if nearestDistance < 0 || nearestDistance > config.MaxUnnamedSuggestionDistanceInMeters {
+			continue
+		}
+		missingLines, _ := compareRouteRef(strings.Join(elementLines, ";"), getLineNames(stop.Lines))
+		var sharedLines []string
+		isMissing := make(map[string]bool)
+		for _, line := range missingLines {
+			isMissing[line] = true
+		}
+		for _, line := range getLineNames(stop.Lines) {
+			if !isMissing[line] {
+				sharedLines = append(sharedLines, line)
+			}
+		}
+		candidates = append(candidates, NameSuggestion{Stop: stop, Distance: nearestDistance, SharedLines: sharedLines})
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	sort.SliceStable(candidates, func(a, b int) bool {
+		if len(candidates[a].SharedLines) != len(candidates[b].SharedLines) {
+			return len(candidates[a].SharedLines) > len(candidates[b].SharedLines)
+		}
+		return candidates[a].Distance < candidates[b].Distance
+	})
+	return &candidates[0]
+}
+
+// getJosmAddTagsLink returns a link which loads the object in JOSM and adds the tag to it
+func getJosmAddTagsLink(e OsmElement, key string, value string) string {
+	objectID := string(e.Type[0]) + strconv.FormatInt(e.ID, 10)
+	return "<a href=\"http://127.0.0.1:8111/load_object?new_layer=false&objects=" + objectID + "&addtags=" + url.QueryEscape(key+"="+value) + "\" target=\"hiddenIframe\" title=\"add " + key + " in JOSM\">(add in JOSM)</a>"
+}
+
+// getNameSuggestionText describes the suggestion for the report
+func getNameSuggestionText(e OsmElement, suggestion NameSuggestion) string {
+	name := getCanonicalName(suggestion.Stop.Name)
+	text := fmt.Sprintf("might be VVR stop %s (%.0f m away", html.EscapeString(suggestion.Stop.Name), suggestion.Distance)
+	if len(suggestion.SharedLines) > 0 {
+		text += ", shared lines " + html.EscapeString(strings.Join(suggestion.SharedLines, ", "))
+	}
+	return text + "): <code>name=" + html.EscapeString(name) + "</code> " + getJosmAddTagsLink(e, "name", name)
+}
@@ -1,10 +1,6 @@
 package main
 
 import (
-	"log"
-	"regexp"
-	"sort"
-	"strconv"
 	"strings"
 )
 
@@ -17,30 +13,60 @@ func getCityResultFromData(cityName string, vvr VvrData) *VvrCity {
 	return nil
 }
 
+// NameMatch tells how an OSM name matched a VVR name or why it did not
+type NameMatch struct {
+	IsMatching bool
+	// City is the city prefixed to the OSM name for matching, if any
+	City string
+}
+
 func doesOsmElementMatchVvrElement(osm OsmElement, vvrName string, cities []string) bool {
-	if len(searchStopName) != len(replaceStopName) {
-		log.Panicln("search and replace arrays do not have the same length")
-	}
-	osmNameCleaned := strings.ToLower(osm.Tags.Name)
-	vvrNameCleaned := strings.ToLower(vvrName)
+	return matchOsmNameToVvrName(osm, vvrName, cities).IsMatching
+}
+
+func matchOsmNameToVvrName(osm OsmElement, vvrName string, cities []string) NameMatch {
+	var match NameMatch
 	// replace abbreviations, special chars etc. to harmonize the names
-	for i := 0; i < len(searchStopName); i++ {
-		osmNameCleaned = strings.ReplaceAll(osmNameCleaned, searchStopName[i], replaceStopName[i])
-		vvrNameCleaned = strings.ReplaceAll(vvrNameCleaned, searchStopName[i], replaceStopName[i])
-	}
-	osmNameCleaned = strings.TrimSpace(osmNameCleaned)
-	vvrNameCleaned = strings.TrimSpace(vvrNameCleaned)
+	osmNameCleaned := normalizeStopName(osm.Tags.Name())
+	vvrNameCleaned := normalizeStopName(vvrName)
 	// exact match
 	if osmNameCleaned == vvrNameCleaned {
-		return true
+		match.IsMatching = true
+		return match
 	}
 	// prefix OSM name with a city
 	for i := 0; i < len(cities); i++ {
-		if strings.ToLower(cities[i])+" "+osmNameCleaned == vvrNameCleaned {
-			return true
+		if normalizeStopName(cities[i])+" "+osmNameCleaned != vvrNameCleaned {
+			continue
 		}
+		match.IsMatching = true
+		match.City = cities[i]
+		return match
+	}
+	return match
+}
+
+// getDhidOfVvrStop returns the DHID of a VVR stop, either from the config or
+// the VVR ID itself if it is already in IFOPT format
+func getDhidOfVvrStop(stop VvrBusStop) string {
+	if dhid, exists := config.VvrDhids[stop.ID]; exists {
+		return dhid
+	}
+	if ifoptRegex.MatchString(stop.ID) {
+		return stop.ID
 	}
-	return false
+	return ""
+}
+
+// doesIfoptBelongToDhid reports whether ifopt is the DHID of the stop itself or
+// one of its areas or quays, e.g. de:13073:1234:1:1 belongs to de:13073:1234
+func doesIfoptBelongToDhid(ifopt string, dhid string) bool {
+	if ifopt == "" || dhid == "" {
+		return false
+	}
+	ifopt = strings.ToLower(strings.TrimSpace(ifopt))
+	dhid = strings.ToLower(strings.TrimSpace(dhid))
+	return ifopt == dhid || strings.HasPrefix(ifopt, dhid+":")
 }
 
 func doesNameExistAlreadyInArray(mbs []MatchedBusStop, name string) int {
@@ -51,29 +77,3 @@ func doesNameExistAlreadyInArray(mbs []MatchedBusStop, name string) int {
 	}
 	return -1
 }
-
-func convertLinienToRouteRef(linien string) (string, error) {
-	routeRef := ""
-	if linien == "" {
-		return routeRef, nil
-	}
-	var linesArr []int
-	var err error
-	var re = regexp.MustCompile(`<span.*?>([0-9]+)</span.*?>`)
-	res := re.FindAllStringSubmatch(linien, -1)
-	for i := range res {
-		line, err := strconv.Atoi(res[i][1])
-		if err != nil {
-			return "", err
-		}
-		linesArr = append(linesArr, line)
-	}
-	sort.Ints(linesArr)
-	for i := 0; i < len(linesArr); i++ {
-		routeRef += strconv.Itoa(linesArr[i])
-		if i < len(linesArr)-1 {
-			routeRef += ";"
-		}
-	}
-	return routeRef, err
-}
@@ -0,0 +1,25 @@
+package main
+
+import "testing"
+
+func TestDoesOsmElementMatchVvrElement(t *testing.T) {
+	cities := []string{"Stralsund", "Vitte", "Bergen"}
+	tests := []struct {
+		tags     OsmTags
+		vvrName  string
+		matching bool
+	}{
+		{OsmTags{"name": "Stralsund, Hauptbahnhof"}, "Stralsund, Hauptbahnhof", true},
+		{OsmTags{"name": "Hauptbahnhof"}, "Stralsund, Hauptbahnhof", true},
+		{OsmTags{"name": "Hauptbahnhof"}, "Bergen, Hauptbahnhof", true},
+		{OsmTags{"name": "Hauptbahnhof"}, "Grimmen, Hauptbahnhof", false},
+		// the municipality tags do not prevent a match, the report warns about them instead
+		{OsmTags{"name": "Hafen", "addr:city": "Insel Hiddensee"}, "Vitte, Hafen", true},
+		{OsmTags{"name": "Hafen", "is_in": "Insel Hiddensee,Vorpommern-Rügen"}, "Vitte, Hafen", true},
+	}
+	for _, test := range tests {
+		if matching := doesOsmElementMatchVvrElement(OsmElement{Tags: test.tags}, test.vvrName, cities); matching != test.matching {
+			t.Errorf("doesOsmElementMatchVvrElement(%v, %q) = %v, want %v", test.tags, test.vvrName, matching, test.matching)
+		}
+	}
+}